@@ -0,0 +1,34 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address
+
+import (
+	"net/netip"
+	"slices"
+)
+
+// Anchor returns a deterministic representative address for the set, eg. to
+// use as a stable key for a host with multiple addresses. The anchor is the
+// numerically smallest address of the preferred family, or of the other
+// family if there are no addresses of the preferred family. The result does
+// not depend on the order of addrs. It returns false if addrs is empty.
+func Anchor(addrs []netip.Addr, preferIPv6 bool) (netip.Addr, bool) {
+	preferred, other := "ip4", "ip6"
+	if preferIPv6 {
+		preferred, other = other, preferred
+	}
+
+	for _, network := range []string{preferred, other} {
+		if candidates := FilterByNetwork(addrs, network); len(candidates) > 0 {
+			return slices.MinFunc(candidates, netip.Addr.Compare), true
+		}
+	}
+	return netip.Addr{}, false
+}
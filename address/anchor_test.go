@@ -0,0 +1,52 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address_test
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+
+	"github.com/noisysockets/util/address"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnchor(t *testing.T) {
+	addrs := []netip.Addr{
+		netip.MustParseAddr("192.168.1.10"),
+		netip.MustParseAddr("fd00::2"),
+		netip.MustParseAddr("10.0.0.1"),
+		netip.MustParseAddr("fd00::1"),
+	}
+
+	anchor, ok := address.Anchor(addrs, true)
+	require.True(t, ok)
+	require.Equal(t, netip.MustParseAddr("fd00::1"), anchor)
+
+	anchor, ok = address.Anchor(addrs, false)
+	require.True(t, ok)
+	require.Equal(t, netip.MustParseAddr("10.0.0.1"), anchor)
+
+	// The result doesn't depend on the order of the input.
+	reversed := slices.Clone(addrs)
+	slices.Reverse(reversed)
+
+	anchor, ok = address.Anchor(reversed, true)
+	require.True(t, ok)
+	require.Equal(t, netip.MustParseAddr("fd00::1"), anchor)
+
+	// Fall back to the other family.
+	anchor, ok = address.Anchor(addrs[:1], true)
+	require.True(t, ok)
+	require.Equal(t, netip.MustParseAddr("192.168.1.10"), anchor)
+
+	_, ok = address.Anchor(nil, true)
+	require.False(t, ok)
+}
@@ -0,0 +1,96 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+)
+
+// Family tags used by the binary encoding.
+const (
+	binaryTagInvalid   byte = 0
+	binaryTagIPv4      byte = 4
+	binaryTagIPv6      byte = 6
+	binaryTagIPv6Zoned byte = 7
+)
+
+// MarshalBinary encodes the addresses into a compact binary form. Each
+// address is encoded as a one byte family tag followed by its 4 or 16 byte
+// representation. IPv6 addresses with a zone are additionally followed by the
+// uvarint length of the zone and the zone itself. The zero netip.Addr is
+// encoded as a lone tag.
+func MarshalBinary(addrs []netip.Addr) []byte {
+	b := make([]byte, 0, len(addrs)*17)
+	for _, addr := range addrs {
+		switch {
+		case addr.Is4():
+			b = append(b, binaryTagIPv4)
+			b = append(b, addr.AsSlice()...)
+		case addr.Is6() && addr.Zone() != "":
+			b = append(b, binaryTagIPv6Zoned)
+			b = append(b, addr.AsSlice()...)
+			b = binary.AppendUvarint(b, uint64(len(addr.Zone())))
+			b = append(b, addr.Zone()...)
+		case addr.Is6():
+			b = append(b, binaryTagIPv6)
+			b = append(b, addr.AsSlice()...)
+		default:
+			b = append(b, binaryTagInvalid)
+		}
+	}
+	return b
+}
+
+// UnmarshalBinary decodes addresses encoded with MarshalBinary.
+func UnmarshalBinary(b []byte) ([]netip.Addr, error) {
+	var addrs []netip.Addr
+	for offset := 0; offset < len(b); {
+		tag := b[offset]
+		offset++
+
+		var n int
+		switch tag {
+		case binaryTagInvalid:
+			addrs = append(addrs, netip.Addr{})
+			continue
+		case binaryTagIPv4:
+			n = 4
+		case binaryTagIPv6, binaryTagIPv6Zoned:
+			n = 16
+		default:
+			return nil, fmt.Errorf("unknown address family tag %d at offset %d", tag, offset-1)
+		}
+
+		if len(b)-offset < n {
+			return nil, fmt.Errorf("truncated address at offset %d: need %d bytes, have %d", offset, n, len(b)-offset)
+		}
+		addr, _ := netip.AddrFromSlice(b[offset : offset+n])
+		offset += n
+
+		if tag == binaryTagIPv6Zoned {
+			zoneLen, m := binary.Uvarint(b[offset:])
+			if m <= 0 {
+				return nil, fmt.Errorf("invalid zone length at offset %d", offset)
+			}
+			offset += m
+
+			if uint64(len(b)-offset) < zoneLen {
+				return nil, fmt.Errorf("truncated zone at offset %d: need %d bytes, have %d", offset, zoneLen, len(b)-offset)
+			}
+			addr = addr.WithZone(string(b[offset : offset+int(zoneLen)]))
+			offset += int(zoneLen)
+		}
+
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/address"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBinary(t *testing.T) {
+	addrs := []netip.Addr{
+		netip.MustParseAddr("192.168.1.1"),
+		netip.MustParseAddr("fd00::1"),
+		netip.MustParseAddr("fe80::1%eth0"),
+		netip.MustParseAddr("::ffff:10.0.0.1"),
+		{},
+	}
+
+	b := address.MarshalBinary(addrs)
+	require.Len(t, b, 5+17+(17+1+4)+17+1)
+
+	decoded, err := address.UnmarshalBinary(b)
+	require.NoError(t, err)
+	require.Equal(t, addrs, decoded)
+
+	decoded, err = address.UnmarshalBinary(nil)
+	require.NoError(t, err)
+	require.Empty(t, decoded)
+
+	t.Run("Truncated", func(t *testing.T) {
+		for i := 1; i < len(b); i++ {
+			// Truncating at an address boundary is valid.
+			if i == 5 || i == 22 || i == 44 || i == 61 {
+				continue
+			}
+			_, err := address.UnmarshalBinary(b[:i])
+			require.Error(t, err, i)
+		}
+	})
+
+	t.Run("Unknown Family", func(t *testing.T) {
+		_, err := address.UnmarshalBinary([]byte{42, 1, 2, 3, 4})
+		require.ErrorContains(t, err, "unknown address family tag 42")
+	})
+}
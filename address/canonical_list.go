@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address
+
+import (
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// CanonicalList returns a canonical, reproducible string representation of a
+// set of addresses, suitable for logging and test assertions. The format is
+// stable and will not change:
+//
+//	[<ipv6>, <ipv6> | <ipv4>, <ipv4>]
+//
+// IPv4-mapped IPv6 addresses are unmapped, duplicates and invalid addresses
+// are dropped, and the addresses of each family are sorted in ascending order.
+// The separator is omitted if either family is absent, eg. "[10.0.0.1]", and
+// an empty set is rendered as "[]".
+func CanonicalList(addrs []netip.Addr) string {
+	var v4, v6 []netip.Addr
+	for _, addr := range addrs {
+		addr = addr.Unmap()
+		if !addr.IsValid() {
+			continue
+		}
+		if addr.Is4() {
+			v4 = append(v4, addr)
+		} else {
+			v6 = append(v6, addr)
+		}
+	}
+
+	var families []string
+	for _, family := range [][]netip.Addr{v6, v4} {
+		if len(family) == 0 {
+			continue
+		}
+
+		slices.SortFunc(family, netip.Addr.Compare)
+		family = slices.Compact(family)
+
+		strs := make([]string, len(family))
+		for i, addr := range family {
+			strs[i] = addr.String()
+		}
+		families = append(families, strings.Join(strs, ", "))
+	}
+
+	return "[" + strings.Join(families, " | ") + "]"
+}
@@ -0,0 +1,41 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/address"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalList(t *testing.T) {
+	addrs := []netip.Addr{
+		netip.MustParseAddr("10.0.0.2"),
+		netip.MustParseAddr("2001:db8::2"),
+		netip.MustParseAddr("::ffff:10.0.0.1"),
+		netip.MustParseAddr("2001:db8::1"),
+		netip.MustParseAddr("10.0.0.2"),
+		{},
+	}
+	require.Equal(t, "[2001:db8::1, 2001:db8::2 | 10.0.0.1, 10.0.0.2]", address.CanonicalList(addrs))
+
+	// The order of the input does not matter.
+	reversed := make([]netip.Addr, len(addrs))
+	for i, addr := range addrs {
+		reversed[len(addrs)-1-i] = addr
+	}
+	require.Equal(t, address.CanonicalList(addrs), address.CanonicalList(reversed))
+
+	require.Equal(t, "[10.0.0.1]", address.CanonicalList([]netip.Addr{netip.MustParseAddr("10.0.0.1")}))
+	require.Equal(t, "[fe80::1%eth0]", address.CanonicalList([]netip.Addr{netip.MustParseAddr("fe80::1%eth0")}))
+	require.Equal(t, "[]", address.CanonicalList(nil))
+}
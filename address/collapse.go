@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address
+
+import (
+	"net/netip"
+	"slices"
+)
+
+// CollapseToPrefixLen groups the given addresses into prefixes no longer than
+// maxBits, eg. collapsing a list of hosts into /24s. This may include
+// addresses that were not in the list. Duplicate prefixes are removed, and
+// adjacent prefixes that together make up a larger prefix are merged, so the
+// result is the minimal set of such prefixes covering all of the addresses.
+//
+// The result is sorted with IPv4 prefixes before IPv6 prefixes, IPv4-mapped
+// IPv6 addresses are treated as IPv4.
+func CollapseToPrefixLen(addrs []netip.Addr, maxBits int) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(addrs))
+	for _, addr := range addrs {
+		addr = addr.Unmap()
+		if !addr.IsValid() {
+			continue
+		}
+		prefix, err := addr.Prefix(min(max(maxBits, 0), addr.BitLen()))
+		if err != nil {
+			continue
+		}
+		prefixes = append(prefixes, prefix)
+	}
+
+	slices.SortFunc(prefixes, func(a, b netip.Prefix) int {
+		if c := a.Addr().Compare(b.Addr()); c != 0 {
+			return c
+		}
+		return a.Bits() - b.Bits()
+	})
+	prefixes = slices.Compact(prefixes)
+
+	// As the prefixes are all the same length (per family) and sorted, any
+	// siblings will be adjacent.
+	collapsed := prefixes[:0]
+	for _, prefix := range prefixes {
+		collapsed = append(collapsed, prefix)
+		for len(collapsed) >= 2 {
+			a, b := collapsed[len(collapsed)-2], collapsed[len(collapsed)-1]
+			if a.Bits() != b.Bits() || a.Bits() == 0 {
+				break
+			}
+			parent := netip.PrefixFrom(a.Addr(), a.Bits()-1).Masked()
+			if !parent.Contains(b.Addr()) {
+				break
+			}
+			collapsed = append(collapsed[:len(collapsed)-2], parent)
+		}
+	}
+
+	return collapsed
+}
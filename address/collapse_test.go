@@ -0,0 +1,51 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/address"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollapseToPrefixLen(t *testing.T) {
+	addrs := []netip.Addr{
+		netip.MustParseAddr("fd00::1"),
+		netip.MustParseAddr("192.168.1.10"),
+		netip.MustParseAddr("192.168.1.20"),
+		netip.MustParseAddr("10.0.0.1"),
+		netip.MustParseAddr("192.168.3.1"),
+		netip.MustParseAddr("192.168.2.1"),
+		netip.MustParseAddr("::ffff:10.0.0.2"),
+		netip.MustParseAddr("fd00::2"),
+	}
+
+	require.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/24"),
+		netip.MustParsePrefix("192.168.1.0/24"),
+		// 192.168.2.0/24 and 192.168.3.0/24 are merged.
+		netip.MustParsePrefix("192.168.2.0/23"),
+		netip.MustParsePrefix("fd00::/24"),
+	}, address.CollapseToPrefixLen(addrs, 24))
+
+	// Prefix lengths are limited to the address length.
+	require.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.1/32"),
+		netip.MustParsePrefix("fd00::/64"),
+	}, address.CollapseToPrefixLen([]netip.Addr{
+		netip.MustParseAddr("fd00::1"),
+		netip.MustParseAddr("10.0.0.1"),
+		netip.MustParseAddr("10.0.0.1"),
+	}, 64))
+
+	require.Empty(t, address.CollapseToPrefixLen(nil, 24))
+}
@@ -0,0 +1,50 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address
+
+import (
+	"errors"
+	"net/netip"
+
+	"github.com/noisysockets/util/uint128"
+)
+
+// CommonPrefixLen returns the number of leading bits shared by all of the
+// given addresses. All addresses must be of the same family (IPv4-mapped IPv6
+// addresses are treated as IPv4).
+func CommonPrefixLen(addrs []netip.Addr) (int, error) {
+	if len(addrs) == 0 {
+		return 0, errors.New("no addresses")
+	}
+
+	first := addrs[0].Unmap()
+	if !first.IsValid() {
+		return 0, errors.New("invalid address")
+	}
+	firstBytes := first.As16()
+	firstVal := uint128.FromBytesBE(firstBytes[:])
+
+	// Accumulate every bit that differs from the first address.
+	var diff uint128.Uint128
+	for _, addr := range addrs[1:] {
+		addr = addr.Unmap()
+		if !addr.IsValid() {
+			return 0, errors.New("invalid address")
+		}
+		if addr.Is4() != first.Is4() {
+			return 0, errors.New("addresses are of mixed families")
+		}
+		addrBytes := addr.As16()
+		diff = diff.Or(firstVal.Xor(uint128.FromBytesBE(addrBytes[:])))
+	}
+
+	// IPv4 addresses occupy the last 32 bits of their 16 byte representation.
+	return diff.LeadingZeros() - (128 - first.BitLen()), nil
+}
@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/address"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommonPrefixLen(t *testing.T) {
+	t.Run("IPv4", func(t *testing.T) {
+		n, err := address.CommonPrefixLen([]netip.Addr{
+			netip.MustParseAddr("10.0.0.1"),
+			netip.MustParseAddr("10.0.15.200"),
+			netip.MustParseAddr("10.0.8.1"),
+		})
+		require.NoError(t, err)
+		require.Equal(t, 20, n)
+
+		n, err = address.CommonPrefixLen([]netip.Addr{
+			netip.MustParseAddr("10.0.0.1"),
+			netip.MustParseAddr("192.168.0.1"),
+		})
+		require.NoError(t, err)
+		require.Equal(t, 0, n)
+	})
+
+	t.Run("IPv6", func(t *testing.T) {
+		n, err := address.CommonPrefixLen([]netip.Addr{
+			netip.MustParseAddr("fd00:1234::1"),
+			netip.MustParseAddr("fd00:1234:0fff::1"),
+		})
+		require.NoError(t, err)
+		require.Equal(t, 36, n)
+	})
+
+	t.Run("Single", func(t *testing.T) {
+		n, err := address.CommonPrefixLen([]netip.Addr{netip.MustParseAddr("10.0.0.1")})
+		require.NoError(t, err)
+		require.Equal(t, 32, n)
+
+		n, err = address.CommonPrefixLen([]netip.Addr{netip.MustParseAddr("fd00::1")})
+		require.NoError(t, err)
+		require.Equal(t, 128, n)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err := address.CommonPrefixLen(nil)
+		require.Error(t, err)
+
+		_, err = address.CommonPrefixLen([]netip.Addr{
+			netip.MustParseAddr("10.0.0.1"),
+			netip.MustParseAddr("fd00::1"),
+		})
+		require.Error(t, err)
+
+		_, err = address.CommonPrefixLen([]netip.Addr{{}})
+		require.Error(t, err)
+
+		_, err = address.CommonPrefixLen([]netip.Addr{
+			netip.MustParseAddr("fd00::1"),
+			{},
+		})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,39 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/noisysockets/util/cidr"
+)
+
+// ExpandPrefix returns every address contained in the given prefix. If the
+// prefix contains more than limit addresses an error is returned (without
+// enumerating them).
+func ExpandPrefix(prefix netip.Prefix, limit int) ([]netip.Addr, error) {
+	if !prefix.IsValid() {
+		return nil, fmt.Errorf("invalid prefix: %s", prefix)
+	}
+
+	count := cidr.HostCount(prefix)
+	if limit < 0 || count.Cmp64(uint64(limit)) > 0 {
+		return nil, fmt.Errorf("prefix %s contains %s addresses, more than the limit of %d",
+			prefix, count, limit)
+	}
+
+	addrs := make([]netip.Addr, 0, count.Lo)
+	for addr := prefix.Masked().Addr(); len(addrs) < int(count.Lo); addr = addr.Next() {
+		addrs = append(addrs, addr)
+	}
+
+	return addrs, nil
+}
@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/address"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandPrefix(t *testing.T) {
+	t.Run("IPv4", func(t *testing.T) {
+		addrs, err := address.ExpandPrefix(netip.MustParsePrefix("10.0.0.5/30"), 4)
+		require.NoError(t, err)
+
+		require.Equal(t, []netip.Addr{
+			netip.MustParseAddr("10.0.0.4"),
+			netip.MustParseAddr("10.0.0.5"),
+			netip.MustParseAddr("10.0.0.6"),
+			netip.MustParseAddr("10.0.0.7"),
+		}, addrs)
+	})
+
+	t.Run("IPv6", func(t *testing.T) {
+		addrs, err := address.ExpandPrefix(netip.MustParsePrefix("fd00::/127"), 10)
+		require.NoError(t, err)
+
+		require.Equal(t, []netip.Addr{
+			netip.MustParseAddr("fd00::"),
+			netip.MustParseAddr("fd00::1"),
+		}, addrs)
+	})
+
+	t.Run("Single Address", func(t *testing.T) {
+		addrs, err := address.ExpandPrefix(netip.MustParsePrefix("255.255.255.255/32"), 1)
+		require.NoError(t, err)
+
+		require.Equal(t, []netip.Addr{netip.MustParseAddr("255.255.255.255")}, addrs)
+	})
+
+	t.Run("Limit Exceeded", func(t *testing.T) {
+		_, err := address.ExpandPrefix(netip.MustParsePrefix("10.0.0.0/24"), 255)
+		require.Error(t, err)
+
+		_, err = address.ExpandPrefix(netip.MustParsePrefix("::/0"), 1000)
+		require.Error(t, err)
+	})
+}
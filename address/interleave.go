@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address
+
+import "net/netip"
+
+// Interleave returns a copy of addrs with the address families alternated, as
+// recommended by RFC 8305 (Happy Eyeballs). The first address's family leads,
+// and the relative order of addresses within each family is preserved. Once
+// one family is exhausted the remaining addresses of the other follow.
+func Interleave(addrs []netip.Addr) []netip.Addr {
+	if len(addrs) == 0 {
+		return nil
+	}
+
+	var first, second []netip.Addr
+	firstIs4 := addrs[0].Unmap().Is4()
+	for _, addr := range addrs {
+		if addr.Unmap().Is4() == firstIs4 {
+			first = append(first, addr)
+		} else {
+			second = append(second, addr)
+		}
+	}
+
+	interleaved := make([]netip.Addr, 0, len(addrs))
+	for i := 0; i < max(len(first), len(second)); i++ {
+		if i < len(first) {
+			interleaved = append(interleaved, first[i])
+		}
+		if i < len(second) {
+			interleaved = append(interleaved, second[i])
+		}
+	}
+	return interleaved
+}
+
+// LimitPerFamily returns at most maxV4 IPv4 addresses and maxV6 IPv6 addresses
+// from addrs, interleaved by family (see Interleave). The relative order of
+// addresses within each family is preserved, so the first addresses of each
+// family are the ones kept. IPv4-mapped IPv6 addresses count as IPv4.
+func LimitPerFamily(addrs []netip.Addr, maxV4, maxV6 int) []netip.Addr {
+	var limited []netip.Addr
+	var v4, v6 int
+	for _, addr := range addrs {
+		if addr.Unmap().Is4() {
+			if v4 < maxV4 {
+				limited = append(limited, addr)
+				v4++
+			}
+		} else if v6 < maxV6 {
+			limited = append(limited, addr)
+			v6++
+		}
+	}
+	return Interleave(limited)
+}
@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address
+
+import (
+	"net/netip"
+	"slices"
+)
+
+// Intersect returns the addresses present in both a and b, without
+// duplicates and in ascending order. Addresses must match exactly, so an
+// IPv4 address does not match its IPv4-mapped IPv6 form, nor does an address
+// match the same address with a zone.
+func Intersect(a, b []netip.Addr) []netip.Addr {
+	inB := make(map[netip.Addr]struct{}, len(b))
+	for _, addr := range b {
+		inB[addr] = struct{}{}
+	}
+
+	var both []netip.Addr
+	for _, addr := range a {
+		if _, ok := inB[addr]; ok {
+			both = append(both, addr)
+		}
+	}
+
+	slices.SortFunc(both, netip.Addr.Compare)
+	return slices.Compact(both)
+}
+
+// Conflicts returns the addresses claimed by both sources, eg. addresses
+// assigned by DHCP that are also statically configured. It is equivalent to
+// Intersect.
+func Conflicts(a, b []netip.Addr) []netip.Addr {
+	return Intersect(a, b)
+}
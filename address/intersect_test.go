@@ -0,0 +1,48 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/address"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntersect(t *testing.T) {
+	dhcp := []netip.Addr{
+		netip.MustParseAddr("fd00::2"),
+		netip.MustParseAddr("10.0.0.3"),
+		netip.MustParseAddr("10.0.0.1"),
+		netip.MustParseAddr("10.0.0.3"),
+		netip.MustParseAddr("10.0.0.4"),
+	}
+
+	static := []netip.Addr{
+		netip.MustParseAddr("10.0.0.3"),
+		netip.MustParseAddr("fd00::2"),
+		netip.MustParseAddr("10.0.0.1"),
+		netip.MustParseAddr("::ffff:10.0.0.4"),
+		netip.MustParseAddr("10.0.0.1"),
+	}
+
+	expected := []netip.Addr{
+		netip.MustParseAddr("10.0.0.1"),
+		netip.MustParseAddr("10.0.0.3"),
+		netip.MustParseAddr("fd00::2"),
+	}
+
+	require.Equal(t, expected, address.Intersect(dhcp, static))
+	require.Equal(t, expected, address.Intersect(static, dhcp))
+	require.Equal(t, expected, address.Conflicts(dhcp, static))
+
+	require.Empty(t, address.Conflicts(dhcp, nil))
+}
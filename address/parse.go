@@ -0,0 +1,36 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// ParseAddrOrPrefix parses s as either a bare address or a single host prefix
+// (a /32 for IPv4 or a /128 for IPv6) and returns the address. Prefixes that
+// contain more than a single host are rejected.
+func ParseAddrOrPrefix(s string) (netip.Addr, error) {
+	if !strings.Contains(s, "/") {
+		return netip.ParseAddr(s)
+	}
+
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+
+	if !prefix.IsSingleIP() {
+		return netip.Addr{}, fmt.Errorf("prefix %q contains more than a single address", s)
+	}
+
+	return prefix.Addr(), nil
+}
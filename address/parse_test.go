@@ -0,0 +1,56 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/address"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAddrOrPrefix(t *testing.T) {
+	t.Run("Address", func(t *testing.T) {
+		addr, err := address.ParseAddrOrPrefix("10.0.0.1")
+		require.NoError(t, err)
+		require.Equal(t, netip.MustParseAddr("10.0.0.1"), addr)
+
+		addr, err = address.ParseAddrOrPrefix("fd00::1")
+		require.NoError(t, err)
+		require.Equal(t, netip.MustParseAddr("fd00::1"), addr)
+	})
+
+	t.Run("Single Host Prefix", func(t *testing.T) {
+		addr, err := address.ParseAddrOrPrefix("10.0.0.1/32")
+		require.NoError(t, err)
+		require.Equal(t, netip.MustParseAddr("10.0.0.1"), addr)
+
+		addr, err = address.ParseAddrOrPrefix("fd00::1/128")
+		require.NoError(t, err)
+		require.Equal(t, netip.MustParseAddr("fd00::1"), addr)
+	})
+
+	t.Run("Multiple Host Prefix", func(t *testing.T) {
+		_, err := address.ParseAddrOrPrefix("10.0.0.0/24")
+		require.ErrorContains(t, err, "contains more than a single address")
+
+		_, err = address.ParseAddrOrPrefix("fd00::/64")
+		require.Error(t, err)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := address.ParseAddrOrPrefix("not-an-address")
+		require.Error(t, err)
+
+		_, err = address.ParseAddrOrPrefix("10.0.0.1/33")
+		require.Error(t, err)
+	})
+}
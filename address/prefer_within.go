@@ -0,0 +1,28 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address
+
+import "net/netip"
+
+// PreferWithin returns a copy of addrs reordered so that the addresses within
+// the home prefix come first, followed by the rest. The relative order of the
+// addresses within each group is preserved.
+func PreferWithin(addrs []netip.Addr, home netip.Prefix) []netip.Addr {
+	preferred := make([]netip.Addr, 0, len(addrs))
+	var rest []netip.Addr
+	for _, addr := range addrs {
+		if home.Contains(addr) {
+			preferred = append(preferred, addr)
+		} else {
+			rest = append(rest, addr)
+		}
+	}
+	return append(preferred, rest...)
+}
@@ -0,0 +1,41 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/address"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreferWithin(t *testing.T) {
+	addrs := []netip.Addr{
+		netip.MustParseAddr("203.0.113.1"),
+		netip.MustParseAddr("192.168.1.20"),
+		netip.MustParseAddr("fd00::1"),
+		netip.MustParseAddr("192.168.1.10"),
+		netip.MustParseAddr("203.0.113.2"),
+	}
+
+	require.Equal(t, []netip.Addr{
+		netip.MustParseAddr("192.168.1.20"),
+		netip.MustParseAddr("192.168.1.10"),
+		netip.MustParseAddr("203.0.113.1"),
+		netip.MustParseAddr("fd00::1"),
+		netip.MustParseAddr("203.0.113.2"),
+	}, address.PreferWithin(addrs, netip.MustParsePrefix("192.168.1.0/24")))
+
+	// Nothing within the home prefix leaves the order unchanged.
+	require.Equal(t, addrs, address.PreferWithin(addrs, netip.MustParsePrefix("10.0.0.0/8")))
+
+	require.Empty(t, address.PreferWithin(nil, netip.MustParsePrefix("10.0.0.0/8")))
+}
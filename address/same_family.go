@@ -0,0 +1,30 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address
+
+import "net/netip"
+
+// AllSameFamily returns true if every address is of the same family, with
+// IPv4-mapped IPv6 addresses treated as IPv4. Empty and single element lists
+// are trivially of the same family. Invalid addresses belong to no family, so
+// they only match other invalid addresses.
+func AllSameFamily(addrs []netip.Addr) bool {
+	if len(addrs) == 0 {
+		return true
+	}
+
+	first := addrs[0].Unmap().BitLen()
+	for _, addr := range addrs[1:] {
+		if addr.Unmap().BitLen() != first {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,44 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/address"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllSameFamily(t *testing.T) {
+	require.True(t, address.AllSameFamily(nil))
+	require.True(t, address.AllSameFamily([]netip.Addr{netip.MustParseAddr("fd00::1")}))
+
+	require.True(t, address.AllSameFamily([]netip.Addr{
+		netip.MustParseAddr("10.0.0.1"),
+		netip.MustParseAddr("::ffff:192.168.1.1"),
+		netip.MustParseAddr("8.8.8.8"),
+	}))
+
+	require.True(t, address.AllSameFamily([]netip.Addr{
+		netip.MustParseAddr("fd00::1"),
+		netip.MustParseAddr("fe80::1%eth0"),
+	}))
+
+	require.False(t, address.AllSameFamily([]netip.Addr{
+		netip.MustParseAddr("fd00::1"),
+		netip.MustParseAddr("::ffff:192.168.1.1"),
+	}))
+
+	require.False(t, address.AllSameFamily([]netip.Addr{
+		netip.MustParseAddr("10.0.0.1"),
+		{},
+	}))
+}
@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+)
+
+// SameSubnet returns true if both addresses fall within the same subnet of
+// the given prefix length. Both addresses must be of the same family
+// (IPv4-mapped IPv6 addresses are treated as IPv4).
+func SameSubnet(a, b netip.Addr, bits int) (bool, error) {
+	a, b = a.Unmap(), b.Unmap()
+	if !a.IsValid() || !b.IsValid() {
+		return false, errors.New("invalid address")
+	}
+	if a.Is4() != b.Is4() {
+		return false, errors.New("addresses are of mixed families")
+	}
+	if bits < 0 || bits > a.BitLen() {
+		return false, fmt.Errorf("invalid prefix length %d for address family", bits)
+	}
+
+	aPrefix, err := a.Prefix(bits)
+	if err != nil {
+		return false, err
+	}
+	bPrefix, err := b.Prefix(bits)
+	if err != nil {
+		return false, err
+	}
+
+	return aPrefix == bPrefix, nil
+}
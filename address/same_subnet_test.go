@@ -0,0 +1,56 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/address"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSameSubnet(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		bits     int
+		expected bool
+	}{
+		{a: "192.168.1.10", b: "192.168.1.200", bits: 24, expected: true},
+		{a: "192.168.1.10", b: "192.168.2.10", bits: 24, expected: false},
+		{a: "192.168.1.10", b: "192.168.2.10", bits: 22, expected: true},
+		{a: "10.0.0.1", b: "192.168.2.10", bits: 0, expected: true},
+		{a: "10.0.0.1", b: "10.0.0.1", bits: 32, expected: true},
+		{a: "10.0.0.1", b: "10.0.0.2", bits: 32, expected: false},
+		{a: "::ffff:10.0.0.1", b: "10.0.0.2", bits: 24, expected: true},
+		{a: "fd00::1", b: "fd00::ffff", bits: 64, expected: true},
+		{a: "fd00::1", b: "fd00:0:0:1::1", bits: 64, expected: false},
+		{a: "fd00::1", b: "fe80::1", bits: 0, expected: true},
+		{a: "fd00::1", b: "fd00::1", bits: 128, expected: true},
+		{a: "fd00::1", b: "fd00::2", bits: 128, expected: false},
+	}
+
+	for _, tc := range testCases {
+		same, err := address.SameSubnet(netip.MustParseAddr(tc.a), netip.MustParseAddr(tc.b), tc.bits)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, same, "%s %s /%d", tc.a, tc.b, tc.bits)
+	}
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err := address.SameSubnet(netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("fd00::1"), 0)
+		require.Error(t, err)
+
+		_, err = address.SameSubnet(netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.0.0.2"), 33)
+		require.Error(t, err)
+
+		_, err = address.SameSubnet(netip.MustParseAddr("fd00::1"), netip.MustParseAddr("fd00::2"), -1)
+		require.Error(t, err)
+	})
+}
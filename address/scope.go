@@ -0,0 +1,112 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address
+
+import "net/netip"
+
+// Scope is a coarse classification of an address by where it is reachable.
+type Scope int
+
+const (
+	// ScopeInvalid is the scope of the zero (invalid) address.
+	ScopeInvalid Scope = iota
+	// ScopeUnspecified is the scope of the unspecified addresses, 0.0.0.0
+	// and ::.
+	ScopeUnspecified
+	// ScopeLoopback is the scope of loopback addresses, eg. 127.0.0.1.
+	ScopeLoopback
+	// ScopeLinkLocal is the scope of link-local unicast addresses, eg.
+	// 169.254.0.1 and fe80::1.
+	ScopeLinkLocal
+	// ScopePrivate is the scope of private addresses, that is RFC 1918,
+	// shared address space (RFC 6598) and unique local (RFC 4193) addresses.
+	ScopePrivate
+	// ScopeMulticast is the scope of multicast addresses.
+	ScopeMulticast
+	// ScopeGlobal is the scope of every other address.
+	ScopeGlobal
+)
+
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+func (s Scope) String() string {
+	switch s {
+	case ScopeInvalid:
+		return "invalid"
+	case ScopeUnspecified:
+		return "unspecified"
+	case ScopeLoopback:
+		return "loopback"
+	case ScopeLinkLocal:
+		return "link-local"
+	case ScopePrivate:
+		return "private"
+	case ScopeMulticast:
+		return "multicast"
+	case ScopeGlobal:
+		return "global"
+	default:
+		return "unknown"
+	}
+}
+
+// Classify returns the scope of the address. IPv4-mapped IPv6 addresses are
+// classified as IPv4.
+func Classify(addr netip.Addr) Scope {
+	addr = addr.Unmap()
+	switch {
+	case !addr.IsValid():
+		return ScopeInvalid
+	case addr.IsUnspecified():
+		return ScopeUnspecified
+	case addr.IsLoopback():
+		return ScopeLoopback
+	case addr.IsMulticast():
+		return ScopeMulticast
+	case addr.IsLinkLocalUnicast():
+		return ScopeLinkLocal
+	case addr.IsPrivate() || sharedAddressSpace.Contains(addr):
+		return ScopePrivate
+	default:
+		return ScopeGlobal
+	}
+}
+
+// FilterByScope returns the addresses with the given scope, in their original
+// order.
+func FilterByScope(addrs []netip.Addr, scope Scope) []netip.Addr {
+	var filtered []netip.Addr
+	for _, addr := range addrs {
+		if Classify(addr) == scope {
+			filtered = append(filtered, addr)
+		}
+	}
+	return filtered
+}
+
+// BucketByScope classifies the addresses in a single pass, returning them
+// grouped by scope. Duplicate addresses are dropped, an IPv4-mapped IPv6
+// address being a duplicate of its IPv4 address (the first seen is kept), and
+// the addresses in each bucket keep their original order. Scopes without any
+// addresses are omitted from the map.
+func BucketByScope(addrs []netip.Addr) map[Scope][]netip.Addr {
+	buckets := make(map[Scope][]netip.Addr)
+	seen := make(map[netip.Addr]struct{}, len(addrs))
+	for _, addr := range addrs {
+		if _, ok := seen[addr.Unmap()]; ok {
+			continue
+		}
+		seen[addr.Unmap()] = struct{}{}
+
+		scope := Classify(addr)
+		buckets[scope] = append(buckets[scope], addr)
+	}
+	return buckets
+}
@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/address"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassify(t *testing.T) {
+	tests := map[string]address.Scope{
+		"0.0.0.0":          address.ScopeUnspecified,
+		"::":               address.ScopeUnspecified,
+		"127.0.0.1":        address.ScopeLoopback,
+		"::1":              address.ScopeLoopback,
+		"169.254.1.1":      address.ScopeLinkLocal,
+		"fe80::1%eth0":     address.ScopeLinkLocal,
+		"10.1.2.3":         address.ScopePrivate,
+		"100.64.0.1":       address.ScopePrivate,
+		"fd00::1":          address.ScopePrivate,
+		"::ffff:192.0.2.1": address.ScopeGlobal,
+		"::ffff:10.0.0.1":  address.ScopePrivate,
+		"224.0.0.1":        address.ScopeMulticast,
+		"ff02::1":          address.ScopeMulticast,
+		"8.8.8.8":          address.ScopeGlobal,
+		"2001:4860::8888":  address.ScopeGlobal,
+	}
+	for addr, expected := range tests {
+		require.Equal(t, expected, address.Classify(netip.MustParseAddr(addr)), addr)
+	}
+
+	require.Equal(t, address.ScopeInvalid, address.Classify(netip.Addr{}))
+	require.Equal(t, "link-local", address.ScopeLinkLocal.String())
+}
+
+func TestBucketByScope(t *testing.T) {
+	addrs := []netip.Addr{
+		netip.MustParseAddr("8.8.8.8"),
+		netip.MustParseAddr("10.0.0.1"),
+		netip.MustParseAddr("2001:4860::8888"),
+		netip.MustParseAddr("8.8.8.8"),
+		netip.MustParseAddr("fd00::1"),
+		netip.MustParseAddr("::ffff:10.0.0.1"),
+	}
+
+	require.Equal(t, map[address.Scope][]netip.Addr{
+		address.ScopeGlobal: {
+			netip.MustParseAddr("8.8.8.8"),
+			netip.MustParseAddr("2001:4860::8888"),
+		},
+		address.ScopePrivate: {
+			netip.MustParseAddr("10.0.0.1"),
+			netip.MustParseAddr("fd00::1"),
+		},
+	}, address.BucketByScope(addrs))
+
+	// Unlike BucketByScope, FilterByScope keeps duplicates.
+	require.Equal(t, []netip.Addr{
+		netip.MustParseAddr("10.0.0.1"),
+		netip.MustParseAddr("fd00::1"),
+		netip.MustParseAddr("::ffff:10.0.0.1"),
+	}, address.FilterByScope(addrs, address.ScopePrivate))
+
+	require.Empty(t, address.BucketByScope(nil))
+}
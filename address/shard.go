@@ -0,0 +1,29 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address
+
+import (
+	"hash/fnv"
+	"net/netip"
+)
+
+// Shard returns a deterministic shard index in [0, shards) for the address,
+// derived from a hash of its bytes. The same address always maps to the same
+// shard, across processes, and IPv4-mapped IPv6 addresses map to the same
+// shard as their IPv4 form. Zones are ignored. It panics if shards <= 0.
+func Shard(addr netip.Addr, shards int) int {
+	if shards <= 0 {
+		panic("address: shards must be positive")
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write(addr.Unmap().AsSlice())
+	return int(h.Sum64() % uint64(shards))
+}
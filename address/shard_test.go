@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/address"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShard(t *testing.T) {
+	addr := netip.MustParseAddr("192.168.1.1")
+
+	shard := address.Shard(addr, 16)
+	require.GreaterOrEqual(t, shard, 0)
+	require.Less(t, shard, 16)
+
+	// Stable, and independent of the address form.
+	require.Equal(t, shard, address.Shard(addr, 16))
+	require.Equal(t, shard, address.Shard(netip.MustParseAddr("::ffff:192.168.1.1"), 16))
+	require.Equal(t, address.Shard(netip.MustParseAddr("fe80::1"), 16), address.Shard(netip.MustParseAddr("fe80::1%eth0"), 16))
+
+	// Addresses are spread across the shards.
+	counts := make([]int, 8)
+	for i := 0; i < 8000; i++ {
+		counts[address.Shard(netip.AddrFrom4([4]byte{10, 0, byte(i >> 8), byte(i)}), len(counts))]++
+	}
+	for _, count := range counts {
+		require.InDelta(t, 1000, count, 200)
+	}
+
+	require.Zero(t, address.Shard(addr, 1))
+	require.Panics(t, func() { address.Shard(addr, 0) })
+}
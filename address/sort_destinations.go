@@ -0,0 +1,183 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address
+
+import (
+	"net/netip"
+	"slices"
+)
+
+// Address scopes as defined by RFC 4007 and used by RFC 6724.
+const (
+	scopeLinkLocal = 0x2
+	scopeSiteLocal = 0x5
+	scopeGlobal    = 0xe
+)
+
+var (
+	ipv4LinkLocalMulticast = netip.MustParsePrefix("224.0.0.0/24")
+	ipv6SiteLocal          = netip.MustParsePrefix("fec0::/10")
+)
+
+// policyTableEntry is an entry in the RFC 6724 default policy table.
+type policyTableEntry struct {
+	prefix     netip.Prefix
+	precedence int
+	label      int
+}
+
+// policyTable is the RFC 6724 section 2.1 default policy table.
+var policyTable = []policyTableEntry{
+	{prefix: netip.MustParsePrefix("::1/128"), precedence: 50, label: 0},
+	{prefix: netip.MustParsePrefix("::/0"), precedence: 40, label: 1},
+	{prefix: netip.MustParsePrefix("::ffff:0:0/96"), precedence: 35, label: 4},
+	{prefix: netip.MustParsePrefix("2002::/16"), precedence: 30, label: 2},
+	{prefix: netip.MustParsePrefix("2001::/32"), precedence: 5, label: 5},
+	{prefix: netip.MustParsePrefix("fc00::/7"), precedence: 3, label: 13},
+	{prefix: netip.MustParsePrefix("::/96"), precedence: 1, label: 3},
+	{prefix: netip.MustParsePrefix("fec0::/10"), precedence: 1, label: 11},
+	{prefix: netip.MustParsePrefix("3ffe::/16"), precedence: 1, label: 12},
+}
+
+// SortDestinations returns a copy of addrs sorted according to the RFC 6724
+// destination address selection rules, using source as the candidate source
+// address. The source is only considered for destinations of the same family,
+// a zero source disables the rules that depend on it.
+//
+// Implemented rules are:
+//
+//   - Rule 2: prefer matching scope.
+//   - Rule 5: prefer matching label.
+//   - Rule 6: prefer higher precedence.
+//   - Rule 8: prefer smaller scope.
+//   - Rule 9: use longest matching prefix (IPv6 only).
+//   - Rule 10: otherwise, leave the order unchanged.
+//
+// Rules 1, 3, 4 and 7 require routing table, interface and mobility state
+// that is not available here and are not implemented.
+func SortDestinations(addrs []netip.Addr, source netip.Addr) []netip.Addr {
+	type destination struct {
+		addr   netip.Addr
+		source netip.Addr
+		attrs  policyTableEntry
+		scope  int
+	}
+
+	dsts := make([]destination, len(addrs))
+	for i, addr := range addrs {
+		dsts[i] = destination{
+			addr:  addr,
+			attrs: classifyPolicy(addr),
+			scope: rfc6724Scope(addr),
+		}
+		if source.IsValid() && source.Unmap().Is4() == addr.Unmap().Is4() {
+			dsts[i].source = source
+		}
+	}
+
+	slices.SortStableFunc(dsts, func(a, b destination) int {
+		// Rule 2: Prefer matching scope.
+		aMatch := a.source.IsValid() && a.scope == rfc6724Scope(a.source)
+		bMatch := b.source.IsValid() && b.scope == rfc6724Scope(b.source)
+		if aMatch && !bMatch {
+			return -1
+		} else if !aMatch && bMatch {
+			return 1
+		}
+
+		// Rule 5: Prefer matching label.
+		aMatch = a.source.IsValid() && a.attrs.label == classifyPolicy(a.source).label
+		bMatch = b.source.IsValid() && b.attrs.label == classifyPolicy(b.source).label
+		if aMatch && !bMatch {
+			return -1
+		} else if !aMatch && bMatch {
+			return 1
+		}
+
+		// Rule 6: Prefer higher precedence.
+		if a.attrs.precedence != b.attrs.precedence {
+			return b.attrs.precedence - a.attrs.precedence
+		}
+
+		// Rule 8: Prefer smaller scope.
+		if a.scope != b.scope {
+			return a.scope - b.scope
+		}
+
+		// Rule 9: Use longest matching prefix.
+		if a.source.IsValid() && b.source.IsValid() && a.addr.Is6() && !a.addr.Is4In6() && b.addr.Is6() && !b.addr.Is4In6() {
+			aLen := sourcePrefixLen(a.addr, a.source)
+			bLen := sourcePrefixLen(b.addr, b.source)
+			if aLen != bLen {
+				return bLen - aLen
+			}
+		}
+
+		// Rule 10: Otherwise, leave the order unchanged.
+		return 0
+	})
+
+	sorted := make([]netip.Addr, len(dsts))
+	for i, dst := range dsts {
+		sorted[i] = dst.addr
+	}
+	return sorted
+}
+
+// classifyPolicy returns the policy table entry that best matches addr.
+func classifyPolicy(addr netip.Addr) policyTableEntry {
+	// The policy table is expressed in terms of IPv6, so map IPv4 addresses.
+	if addr.Is4() {
+		addr = netip.AddrFrom16(addr.As16())
+	}
+
+	// The table is tiny, so a linear longest prefix match is fine.
+	var best policyTableEntry
+	for _, entry := range policyTable {
+		if entry.prefix.Contains(addr) && (!best.prefix.IsValid() || entry.prefix.Bits() > best.prefix.Bits()) {
+			best = entry
+		}
+	}
+	return best
+}
+
+// rfc6724Scope returns the RFC 6724 section 3.1 scope of addr. It refines
+// Classify with the multicast and site-local scopes that RFC 6724 tells apart,
+// whereas private addresses (RFC 1918 and unique local) have global scope.
+func rfc6724Scope(addr netip.Addr) int {
+	addr = addr.Unmap()
+	switch Classify(addr) {
+	case ScopeLoopback, ScopeLinkLocal:
+		return scopeLinkLocal
+	case ScopeMulticast:
+		if addr.Is6() {
+			return int(addr.As16()[1] & 0xf)
+		}
+		// IPv4 multicast is treated as global, except for the link-local block.
+		if ipv4LinkLocalMulticast.Contains(addr) {
+			return scopeLinkLocal
+		}
+		return scopeGlobal
+	}
+	if addr.Is6() && ipv6SiteLocal.Contains(addr) {
+		return scopeSiteLocal
+	}
+	return scopeGlobal
+}
+
+// sourcePrefixLen returns the number of leading bits shared by two IPv6
+// addresses, limited to the 64 bit prefix portion of the source.
+func sourcePrefixLen(a, b netip.Addr) int {
+	n, err := CommonPrefixLen([]netip.Addr{a, b})
+	if err != nil {
+		return 0
+	}
+	return min(n, 64)
+}
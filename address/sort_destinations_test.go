@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/address"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortDestinations(t *testing.T) {
+	t.Run("Prefer IPv6", func(t *testing.T) {
+		addrs := []netip.Addr{
+			netip.MustParseAddr("93.184.216.34"),
+			netip.MustParseAddr("2606:2800:220:1:248:1893:25c8:1946"),
+		}
+
+		sorted := address.SortDestinations(addrs, netip.Addr{})
+		require.Equal(t, []netip.Addr{addrs[1], addrs[0]}, sorted)
+
+		// The input should not be modified.
+		require.Equal(t, netip.MustParseAddr("93.184.216.34"), addrs[0])
+	})
+
+	t.Run("Prefer Matching Scope", func(t *testing.T) {
+		addrs := []netip.Addr{
+			netip.MustParseAddr("2001:db8:1::1"),
+			netip.MustParseAddr("fe80::1"),
+		}
+
+		sorted := address.SortDestinations(addrs, netip.MustParseAddr("fe80::2"))
+		require.Equal(t, []netip.Addr{addrs[1], addrs[0]}, sorted)
+	})
+
+	t.Run("Prefer Matching Label", func(t *testing.T) {
+		addrs := []netip.Addr{
+			netip.MustParseAddr("2001:db8:1::1"),
+			netip.MustParseAddr("10.0.0.1"),
+		}
+
+		// With an IPv4 source, only the IPv4 destination has a matching label.
+		sorted := address.SortDestinations(addrs, netip.MustParseAddr("10.0.0.2"))
+		require.Equal(t, []netip.Addr{addrs[1], addrs[0]}, sorted)
+	})
+
+	t.Run("Prefer Higher Precedence", func(t *testing.T) {
+		addrs := []netip.Addr{
+			netip.MustParseAddr("fd00::1"),
+			netip.MustParseAddr("2002:c000:0204::1"),
+			netip.MustParseAddr("::1"),
+		}
+
+		sorted := address.SortDestinations(addrs, netip.Addr{})
+		require.Equal(t, []netip.Addr{addrs[2], addrs[1], addrs[0]}, sorted)
+	})
+
+	t.Run("Longest Matching Prefix", func(t *testing.T) {
+		addrs := []netip.Addr{
+			netip.MustParseAddr("2001:db8:2::1"),
+			netip.MustParseAddr("2001:db8:1::1"),
+		}
+
+		sorted := address.SortDestinations(addrs, netip.MustParseAddr("2001:db8:1::2"))
+		require.Equal(t, []netip.Addr{addrs[1], addrs[0]}, sorted)
+	})
+
+	t.Run("Stable", func(t *testing.T) {
+		addrs := []netip.Addr{
+			netip.MustParseAddr("2001:db8:2::1"),
+			netip.MustParseAddr("2001:db8:1::1"),
+		}
+
+		sorted := address.SortDestinations(addrs, netip.Addr{})
+		require.Equal(t, addrs, sorted)
+	})
+}
@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package address_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/address"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsSpecialPurpose(t *testing.T) {
+	testCases := []struct {
+		addr string
+		name string
+	}{
+		{addr: "0.0.0.0", name: "This host on this network"},
+		{addr: "0.1.2.3", name: "This network"},
+		{addr: "100.64.1.1", name: "Shared Address Space"},
+		{addr: "127.0.0.1", name: "Loopback"},
+		{addr: "169.254.1.1", name: "Link Local"},
+		{addr: "192.168.1.1", name: "Private-Use"},
+		{addr: "198.19.0.1", name: "Benchmarking"},
+		{addr: "192.0.2.1", name: "Documentation (TEST-NET-1)"},
+		{addr: "192.0.0.9", name: "Port Control Protocol Anycast"},
+		{addr: "224.0.0.251", name: "Multicast"},
+		{addr: "255.255.255.255", name: "Limited Broadcast"},
+		{addr: "::1", name: "Loopback Address"},
+		{addr: "::", name: "Unspecified Address"},
+		{addr: "::ffff:8.8.8.8", name: "IPv4-mapped Address"},
+		{addr: "2001:0:4136:e378:8000:63bf:3fff:fdd2", name: "Teredo"},
+		{addr: "2001:db8::1", name: "Documentation"},
+		{addr: "2002:c000:0204::1", name: "6to4"},
+		{addr: "fd00::1", name: "Unique-Local"},
+		{addr: "fe80::1", name: "Link-Local Unicast"},
+		{addr: "ff02::1", name: "Multicast"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.addr, func(t *testing.T) {
+			special, name := address.IsSpecialPurpose(netip.MustParseAddr(tc.addr))
+			require.True(t, special)
+			require.Equal(t, tc.name, name)
+		})
+	}
+
+	for _, addr := range []string{"8.8.8.8", "1.1.1.1", "2606:4700:4700::1111"} {
+		t.Run(addr, func(t *testing.T) {
+			special, name := address.IsSpecialPurpose(netip.MustParseAddr(addr))
+			require.False(t, special)
+			require.Empty(t, name)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr
+
+import (
+	"errors"
+	"net/netip"
+
+	"github.com/noisysockets/util/internal/addrbits"
+	"github.com/noisysockets/util/uint128"
+)
+
+// CoverWithWaste returns the smallest prefix covering all of the given
+// addresses, and the number of addresses in that prefix that are not in the
+// set (duplicates are only counted once). IPv4-mapped IPv6 addresses are
+// treated as IPv4, and all addresses must be of the same family.
+//
+// As the size of ::/0 saturates at uint128.Max (see HostCount), the waste of
+// a ::/0 cover is one less than the true value.
+func CoverWithWaste(addrs []netip.Addr) (netip.Prefix, uint128.Uint128, error) {
+	if len(addrs) == 0 {
+		return netip.Prefix{}, uint128.Zero, errors.New("no addresses")
+	}
+
+	first := addrs[0].Unmap()
+	if !first.IsValid() {
+		return netip.Prefix{}, uint128.Zero, errors.New("invalid address")
+	}
+	firstVal, totalBits := addrbits.FromAddr(first)
+
+	bits := totalBits
+	distinct := make(map[netip.Addr]struct{}, len(addrs))
+	for _, addr := range addrs {
+		addr = addr.Unmap()
+		if !addr.IsValid() {
+			return netip.Prefix{}, uint128.Zero, errors.New("invalid address")
+		}
+		if addr.Is4() != first.Is4() {
+			return netip.Prefix{}, uint128.Zero, errors.New("addresses are of mixed families")
+		}
+		distinct[addr] = struct{}{}
+
+		addrVal, _ := addrbits.FromAddr(addr)
+		bits = min(bits, addrbits.CommonLen(firstVal, addrVal, totalBits))
+	}
+
+	prefix := netip.PrefixFrom(first, bits).Masked()
+	return prefix, HostCount(prefix).Sub64(uint64(len(distinct))), nil
+}
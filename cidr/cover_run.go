@@ -0,0 +1,39 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/noisysockets/util/internal/addrbits"
+	"github.com/noisysockets/util/uint128"
+)
+
+// CoverRun returns the minimal set of prefixes that exactly covers the run of
+// count consecutive addresses beginning at start, in ascending order. An
+// error wrapping ErrInvalidRange is returned if the run would extend past the
+// end of the address space.
+func CoverRun(start netip.Addr, count uint128.Uint128) ([]netip.Prefix, error) {
+	if !start.IsValid() {
+		return nil, fmt.Errorf("%w: invalid address", ErrInvalidRange)
+	}
+	if count.IsZero() {
+		return nil, nil
+	}
+
+	startVal, totalBits := addrbits.FromAddr(start)
+	if count.Sub64(1).Cmp(addrbits.HostMask(totalBits).Sub(startVal)) > 0 {
+		return nil, fmt.Errorf("%w: run of %s addresses from %s overflows the address space", ErrInvalidRange, count, start)
+	}
+
+	end := addrbits.ToAddr(startVal.Add(count.Sub64(1)), totalBits)
+	return RangeToPrefixes(start.WithZone(""), end)
+}
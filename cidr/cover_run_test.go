@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/cidr"
+	"github.com/noisysockets/util/uint128"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoverRun(t *testing.T) {
+	prefixes, err := cidr.CoverRun(netip.MustParseAddr("10.0.0.0"), uint128.From64(256))
+	require.NoError(t, err)
+	require.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/24")}, prefixes)
+
+	// A non power of two run.
+	prefixes, err = cidr.CoverRun(netip.MustParseAddr("10.0.0.4"), uint128.From64(13))
+	require.NoError(t, err)
+	require.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.4/30"),
+		netip.MustParsePrefix("10.0.0.8/29"),
+		netip.MustParsePrefix("10.0.0.16/32"),
+	}, prefixes)
+
+	prefixes, err = cidr.CoverRun(netip.MustParseAddr("fd00::"), uint128.From64(3))
+	require.NoError(t, err)
+	require.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("fd00::/127"),
+		netip.MustParsePrefix("fd00::2/128"),
+	}, prefixes)
+
+	prefixes, err = cidr.CoverRun(netip.MustParseAddr("10.0.0.0"), uint128.Zero)
+	require.NoError(t, err)
+	require.Empty(t, prefixes)
+
+	// Runs up to the very end of the address space are fine.
+	prefixes, err = cidr.CoverRun(netip.MustParseAddr("255.255.255.0"), uint128.From64(256))
+	require.NoError(t, err)
+	require.Equal(t, []netip.Prefix{netip.MustParsePrefix("255.255.255.0/24")}, prefixes)
+
+	prefixes, err = cidr.CoverRun(netip.MustParseAddr("::"), uint128.Max)
+	require.NoError(t, err)
+	require.Len(t, prefixes, 128)
+
+	_, err = cidr.CoverRun(netip.MustParseAddr("255.255.255.0"), uint128.From64(257))
+	require.ErrorIs(t, err, cidr.ErrInvalidRange)
+
+	_, err = cidr.CoverRun(netip.MustParseAddr("::2"), uint128.Max)
+	require.ErrorIs(t, err, cidr.ErrInvalidRange)
+}
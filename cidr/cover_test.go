@@ -0,0 +1,53 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/cidr"
+	"github.com/noisysockets/util/uint128"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoverWithWaste(t *testing.T) {
+	prefix, waste, err := cidr.CoverWithWaste([]netip.Addr{
+		netip.MustParseAddr("10.0.0.1"),
+		netip.MustParseAddr("10.0.0.6"),
+		netip.MustParseAddr("10.0.0.6"),
+		netip.MustParseAddr("::ffff:10.0.0.3"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, netip.MustParsePrefix("10.0.0.0/29"), prefix)
+	require.Equal(t, uint128.From64(5), waste)
+
+	prefix, waste, err = cidr.CoverWithWaste([]netip.Addr{netip.MustParseAddr("fd00::1")})
+	require.NoError(t, err)
+	require.Equal(t, netip.MustParsePrefix("fd00::1/128"), prefix)
+	require.Equal(t, uint128.Zero, waste)
+
+	prefix, waste, err = cidr.CoverWithWaste([]netip.Addr{
+		netip.MustParseAddr("fd00::"),
+		netip.MustParseAddr("fd00::ffff:ffff:ffff:ffff"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, netip.MustParsePrefix("fd00::/64"), prefix)
+	require.Equal(t, uint128.New(^uint64(0)-1, 0), waste)
+
+	_, _, err = cidr.CoverWithWaste(nil)
+	require.Error(t, err)
+
+	_, _, err = cidr.CoverWithWaste([]netip.Addr{
+		netip.MustParseAddr("10.0.0.1"),
+		netip.MustParseAddr("fd00::1"),
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,28 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr
+
+import "net/netip"
+
+// CoveringPrefixes returns every prefix containing the address, one for each
+// prefix length from /0 to /32 (IPv4) or /128 (IPv6), ordered from the least
+// to the most specific.
+func CoveringPrefixes(addr netip.Addr) []netip.Prefix {
+	if !addr.IsValid() {
+		return nil
+	}
+	addr = addr.WithZone("")
+
+	prefixes := make([]netip.Prefix, addr.BitLen()+1)
+	for bits := range prefixes {
+		prefixes[bits] = netip.PrefixFrom(addr, bits).Masked()
+	}
+	return prefixes
+}
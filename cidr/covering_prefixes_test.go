@@ -0,0 +1,39 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/cidr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoveringPrefixes(t *testing.T) {
+	prefixes := cidr.CoveringPrefixes(netip.MustParseAddr("10.1.2.3"))
+	require.Len(t, prefixes, 33)
+	require.Equal(t, netip.MustParsePrefix("0.0.0.0/0"), prefixes[0])
+	require.Equal(t, netip.MustParsePrefix("10.0.0.0/8"), prefixes[8])
+	require.Equal(t, netip.MustParsePrefix("10.1.0.0/16"), prefixes[16])
+	require.Equal(t, netip.MustParsePrefix("10.1.2.3/32"), prefixes[32])
+
+	prefixes = cidr.CoveringPrefixes(netip.MustParseAddr("fe80::1%eth0"))
+	require.Len(t, prefixes, 129)
+	require.Equal(t, netip.MustParsePrefix("::/0"), prefixes[0])
+	require.Equal(t, netip.MustParsePrefix("fe80::/10"), prefixes[10])
+	require.Equal(t, netip.MustParsePrefix("fe80::1/128"), prefixes[128])
+
+	for _, prefix := range prefixes {
+		require.True(t, prefix.Contains(netip.MustParseAddr("fe80::1")))
+	}
+
+	require.Nil(t, cidr.CoveringPrefixes(netip.Addr{}))
+}
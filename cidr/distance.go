@@ -0,0 +1,40 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr
+
+import (
+	"net/netip"
+
+	"github.com/noisysockets/util/internal/addrbits"
+)
+
+// Distance returns the number of leading bits the address shares with the
+// prefix's network address, capped at the prefix length, and whether the
+// address is within the prefix. It quantifies how near a miss an address is,
+// eg. 10.0.1.1 shares 23 bits with 10.0.0.0/24. If the address is within the
+// prefix, it returns prefix.Bits() and true.
+//
+// Addresses and prefixes of different families (including IPv4-mapped IPv6
+// addresses and IPv4 prefixes) share no bits. Any zone on the address is
+// ignored.
+func Distance(addr netip.Addr, prefix netip.Prefix) (int, bool) {
+	addr = addr.WithZone("")
+	if !addr.IsValid() || !prefix.IsValid() || addr.BitLen() != prefix.Addr().BitLen() {
+		return 0, false
+	}
+
+	if prefix.Contains(addr) {
+		return prefix.Bits(), true
+	}
+
+	a, totalBits := addrbits.FromAddr(addr)
+	b, _ := addrbits.FromAddr(prefix.Masked().Addr())
+	return min(addrbits.CommonLen(a, b, totalBits), prefix.Bits()), false
+}
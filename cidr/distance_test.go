@@ -0,0 +1,54 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/cidr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDistance(t *testing.T) {
+	bits, inside := cidr.Distance(netip.MustParseAddr("10.0.0.42"), netip.MustParsePrefix("10.0.0.0/24"))
+	require.True(t, inside)
+	require.Equal(t, 24, bits)
+
+	bits, inside = cidr.Distance(netip.MustParseAddr("10.0.1.1"), netip.MustParsePrefix("10.0.0.0/24"))
+	require.False(t, inside)
+	require.Equal(t, 23, bits)
+
+	bits, inside = cidr.Distance(netip.MustParseAddr("192.168.0.1"), netip.MustParsePrefix("10.0.0.0/24"))
+	require.False(t, inside)
+	require.Equal(t, 0, bits)
+
+	bits, inside = cidr.Distance(netip.MustParseAddr("2001:db8:1::1"), netip.MustParsePrefix("2001:db8::/48"))
+	require.False(t, inside)
+	require.Equal(t, 47, bits)
+
+	// Host bits in the prefix are ignored.
+	bits, inside = cidr.Distance(netip.MustParseAddr("10.0.1.1"), netip.MustParsePrefix("10.0.0.7/24"))
+	require.False(t, inside)
+	require.Equal(t, 23, bits)
+
+	// Zones are ignored.
+	bits, inside = cidr.Distance(netip.MustParseAddr("fe80::1%eth0"), netip.MustParsePrefix("fe80::/64"))
+	require.True(t, inside)
+	require.Equal(t, 64, bits)
+
+	// Mismatched families share no bits.
+	bits, inside = cidr.Distance(netip.MustParseAddr("::ffff:10.0.0.1"), netip.MustParsePrefix("10.0.0.0/24"))
+	require.False(t, inside)
+	require.Equal(t, 0, bits)
+
+	_, inside = cidr.Distance(netip.Addr{}, netip.MustParsePrefix("10.0.0.0/24"))
+	require.False(t, inside)
+}
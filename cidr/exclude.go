@@ -0,0 +1,93 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr
+
+import (
+	"errors"
+	"net/netip"
+	"slices"
+
+	"github.com/noisysockets/util/internal/addrbits"
+	"github.com/noisysockets/util/uint128"
+)
+
+var (
+	// ErrNoUsableAddresses is returned when reservations leave no usable
+	// addresses in a prefix.
+	ErrNoUsableAddresses = errors.New("no usable addresses")
+)
+
+// Exclude returns the minimal set of prefixes covering the given prefix with
+// the addresses of the excluded prefixes removed, in ascending address order.
+// Any host bits are ignored.
+func Exclude(prefix netip.Prefix, excluded ...netip.Prefix) []netip.Prefix {
+	remaining := []netip.Prefix{prefix.Masked()}
+	for _, e := range excluded {
+		var next []netip.Prefix
+		for _, p := range remaining {
+			next = append(next, exclude(p, e.Masked())...)
+		}
+		remaining = next
+	}
+
+	slices.SortFunc(remaining, func(a, b netip.Prefix) int {
+		if c := a.Addr().Compare(b.Addr()); c != 0 {
+			return c
+		}
+		return a.Bits() - b.Bits()
+	})
+	return remaining
+}
+
+// UsablePrefixes returns the minimal set of prefixes covering the given
+// prefix, less its first (network) address if reserveFirst is set and its
+// last (broadcast) address if reserveLast is set.
+func UsablePrefixes(prefix netip.Prefix, reserveFirst, reserveLast bool) ([]netip.Prefix, error) {
+	if !prefix.IsValid() {
+		return nil, errors.New("invalid prefix")
+	}
+
+	start, end := PrefixToRange(prefix)
+
+	var reserved []netip.Prefix
+	if reserveFirst {
+		reserved = append(reserved, netip.PrefixFrom(start, start.BitLen()))
+	}
+	if reserveLast {
+		reserved = append(reserved, netip.PrefixFrom(end, end.BitLen()))
+	}
+
+	usable := Exclude(prefix, reserved...)
+	if len(usable) == 0 {
+		return nil, ErrNoUsableAddresses
+	}
+	return usable, nil
+}
+
+// exclude removes the excluded prefix from the given prefix, both prefixes
+// must be masked.
+func exclude(prefix, excluded netip.Prefix) []netip.Prefix {
+	switch Relation(prefix, excluded) {
+	case RelationDisjoint:
+		return []netip.Prefix{prefix}
+	case RelationEqual, RelationContainedBy:
+		return nil
+	}
+
+	// Walk down from the prefix towards the excluded prefix, keeping the
+	// sibling half at each level.
+	ip, totalBits := addrbits.FromAddr(excluded.Addr())
+	prefixes := make([]netip.Prefix, 0, excluded.Bits()-prefix.Bits())
+	for bits := prefix.Bits() + 1; bits <= excluded.Bits(); bits++ {
+		sibling := ip.Xor(uint128.From64(1).Lsh(uint(totalBits - bits)))
+		prefixes = append(prefixes, netip.PrefixFrom(addrbits.ToAddr(sibling, totalBits), bits).Masked())
+	}
+	return prefixes
+}
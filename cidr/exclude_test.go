@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/cidr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExclude(t *testing.T) {
+	require.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/26"),
+		netip.MustParsePrefix("10.0.0.64/27"),
+		netip.MustParsePrefix("10.0.0.112/28"),
+		netip.MustParsePrefix("10.0.0.128/25"),
+	}, cidr.Exclude(netip.MustParsePrefix("10.0.0.0/24"), netip.MustParsePrefix("10.0.0.96/28")))
+
+	// Excluding a disjoint prefix leaves the prefix untouched.
+	require.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/24")},
+		cidr.Exclude(netip.MustParsePrefix("10.0.0.0/24"), netip.MustParsePrefix("10.0.1.0/24"), netip.MustParsePrefix("fd00::/8")))
+
+	// Excluding a containing prefix leaves nothing.
+	require.Empty(t, cidr.Exclude(netip.MustParsePrefix("10.0.0.0/24"), netip.MustParsePrefix("10.0.0.0/16")))
+}
+
+func TestUsablePrefixes(t *testing.T) {
+	prefix := netip.MustParsePrefix("192.168.1.0/24")
+
+	usable, err := cidr.UsablePrefixes(prefix, true, true)
+	require.NoError(t, err)
+
+	require.Len(t, usable, 14)
+
+	start, _ := cidr.PrefixToRange(usable[0])
+	require.Equal(t, netip.MustParseAddr("192.168.1.1"), start)
+	_, end := cidr.PrefixToRange(usable[len(usable)-1])
+	require.Equal(t, netip.MustParseAddr("192.168.1.254"), end)
+
+	// Every address apart from .0 and .255 should be covered exactly once.
+	for i := 0; i < 256; i++ {
+		addr := netip.AddrFrom4([4]byte{192, 168, 1, byte(i)})
+
+		var matches int
+		for _, p := range usable {
+			if p.Contains(addr) {
+				matches++
+			}
+		}
+
+		if i == 0 || i == 255 {
+			require.Zero(t, matches, addr)
+		} else {
+			require.Equal(t, 1, matches, addr)
+		}
+	}
+
+	usable, err = cidr.UsablePrefixes(prefix, false, false)
+	require.NoError(t, err)
+	require.Equal(t, []netip.Prefix{prefix}, usable)
+
+	_, err = cidr.UsablePrefixes(netip.MustParsePrefix("10.0.0.1/32"), true, false)
+	require.ErrorIs(t, err, cidr.ErrNoUsableAddresses)
+}
@@ -0,0 +1,44 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+)
+
+var (
+	// ErrNoGateway is returned when a prefix is too small to have a gateway
+	// address.
+	ErrNoGateway = errors.New("prefix has no gateway address")
+)
+
+// Gateway returns the conventional gateway address of the prefix, that is its
+// first usable host address (eg. 10.0.0.1 for 10.0.0.0/24). Point-to-point
+// prefixes (/31 and /127) have no reserved network address, so the gateway is
+// the first address of the prefix. Host prefixes (/32 and /128) have no
+// address distinct from the host itself, so an error wrapping ErrNoGateway is
+// returned for them.
+func Gateway(prefix netip.Prefix) (netip.Addr, error) {
+	if !prefix.IsValid() {
+		return netip.Addr{}, errors.New("invalid prefix")
+	}
+	prefix = prefix.Masked()
+
+	switch prefix.Addr().BitLen() - prefix.Bits() {
+	case 0:
+		return netip.Addr{}, fmt.Errorf("%w: %s is a host prefix", ErrNoGateway, prefix)
+	case 1:
+		return Host(prefix, 0)
+	default:
+		return Host(prefix, 1)
+	}
+}
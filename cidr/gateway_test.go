@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/cidr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGateway(t *testing.T) {
+	tests := map[string]string{
+		"10.0.0.0/24":     "10.0.0.1",
+		"10.0.0.7/24":     "10.0.0.1",
+		"192.168.1.4/30":  "192.168.1.5",
+		"192.168.1.4/31":  "192.168.1.4",
+		"192.168.1.5/31":  "192.168.1.4",
+		"0.0.0.0/0":       "0.0.0.1",
+		"2001:db8::/64":   "2001:db8::1",
+		"::/120":          "::1",
+		"2001:db8::/127":  "2001:db8::",
+		"2001:db8::1/127": "2001:db8::",
+		"::/127":          "::",
+	}
+	for prefix, expected := range tests {
+		gateway, err := cidr.Gateway(netip.MustParsePrefix(prefix))
+		require.NoError(t, err, prefix)
+		require.Equal(t, netip.MustParseAddr(expected), gateway, prefix)
+
+		// It's the conventional first host, other than for point-to-point
+		// prefixes where it's the first address.
+		num := 1
+		if bits := netip.MustParsePrefix(prefix).Bits(); bits == 31 || bits == 127 {
+			num = 0
+		}
+		host, err := cidr.Host(netip.MustParsePrefix(prefix).Masked(), num)
+		require.NoError(t, err, prefix)
+		require.Equal(t, host, gateway, prefix)
+	}
+
+	for _, prefix := range []string{"10.0.0.1/32", "0.0.0.0/32", "2001:db8::1/128", "::/128"} {
+		_, err := cidr.Gateway(netip.MustParsePrefix(prefix))
+		require.ErrorIs(t, err, cidr.ErrNoGateway, prefix)
+	}
+
+	_, err := cidr.Gateway(netip.Prefix{})
+	require.Error(t, err)
+}
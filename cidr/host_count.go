@@ -0,0 +1,29 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr
+
+import (
+	"net/netip"
+
+	"github.com/noisysockets/util/internal/addrbits"
+	"github.com/noisysockets/util/uint128"
+)
+
+// HostCount returns the total number of addresses in the given prefix
+// (including any network and broadcast addresses). As the size of ::/0 can't
+// be represented by a uint128, the result saturates at uint128.Max.
+func HostCount(prefix netip.Prefix) uint128.Uint128 {
+	_, totalBits := addrbits.FromAddr(prefix.Addr())
+	hostBits := totalBits - prefix.Bits()
+	if hostBits >= 128 {
+		return uint128.Max
+	}
+	return uint128.From64(1).Lsh(uint(hostBits))
+}
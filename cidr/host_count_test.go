@@ -0,0 +1,33 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/cidr"
+	"github.com/noisysockets/util/uint128"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostCount(t *testing.T) {
+	t.Run("IPv4", func(t *testing.T) {
+		require.Equal(t, uint128.From64(256), cidr.HostCount(netip.MustParsePrefix("192.168.1.0/24")))
+		require.Equal(t, uint128.From64(1), cidr.HostCount(netip.MustParsePrefix("192.168.1.1/32")))
+		require.Equal(t, uint128.From64(1<<32), cidr.HostCount(netip.MustParsePrefix("0.0.0.0/0")))
+	})
+
+	t.Run("IPv6", func(t *testing.T) {
+		require.Equal(t, uint128.New(0, 1), cidr.HostCount(netip.MustParsePrefix("fd00::/64")))
+		require.Equal(t, uint128.From64(1), cidr.HostCount(netip.MustParsePrefix("fd00::1/128")))
+		require.Equal(t, uint128.Max, cidr.HostCount(netip.MustParsePrefix("::/0")))
+	})
+}
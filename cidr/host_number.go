@@ -32,7 +32,7 @@ func Host(prefix netip.Prefix, num int) (netip.Addr, error) {
 	intValBytes := intVal.BytesBE()
 
 	var addr netip.Addr
-	if intVal.Hi == 0 {
+	if prefix.Addr().Is4() {
 		addr = netip.AddrFrom4([4]byte(intValBytes[12:]))
 	} else {
 		addr = netip.AddrFrom16(intValBytes)
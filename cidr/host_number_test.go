@@ -34,5 +34,10 @@ func TestHost(t *testing.T) {
 		require.NoError(t, err)
 
 		require.Equal(t, "fd00::1", addr.String())
+
+		// Low IPv6 addresses remain IPv6.
+		addr, err = cidr.Host(netip.MustParsePrefix("::/120"), 1)
+		require.NoError(t, err)
+		require.Equal(t, "::1", addr.String())
 	})
 }
@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+)
+
+var (
+	// ErrNotNetworkAddr is returned when a prefix has host bits set.
+	ErrNotNetworkAddr = errors.New("prefix is not a network address")
+	// ErrAddrNotInPrefix is returned when an address is not within a prefix.
+	ErrAddrNotInPrefix = errors.New("address is not within prefix")
+	// ErrNoBroadcast is returned when a prefix has no concept of a broadcast
+	// address, ie. it is an IPv6 prefix.
+	ErrNoBroadcast = errors.New("prefix has no broadcast address")
+)
+
+// IsNetworkAddr returns true if the prefix is a proper network address, that
+// is none of its host bits are set (eg. 10.0.0.0/24 rather than 10.0.0.5/24).
+func IsNetworkAddr(prefix netip.Prefix) bool {
+	return prefix.IsValid() && prefix == prefix.Masked()
+}
+
+// RequireNetworkAddr returns an error wrapping ErrNotNetworkAddr if the prefix
+// is not a proper network address.
+func RequireNetworkAddr(prefix netip.Prefix) error {
+	if !IsNetworkAddr(prefix) {
+		return fmt.Errorf("%w: %s (did you mean %s?)", ErrNotNetworkAddr, prefix, prefix.Masked())
+	}
+	return nil
+}
+
+// IsNetworkAddress returns true if addr is the network (first) address of
+// the prefix. Point-to-point (/31 and /127) and host (/32 and /128) prefixes
+// have no reserved network address, so this is always false for them, as it
+// is for addresses outside of the prefix.
+func IsNetworkAddress(addr netip.Addr, prefix netip.Prefix) bool {
+	if !prefix.Contains(addr) || prefix.Bits() >= addr.BitLen()-1 {
+		return false
+	}
+	return addr == prefix.Masked().Addr()
+}
+
+// IsBroadcastAddress returns true if addr is the broadcast (last) address of
+// the IPv4 prefix. Point-to-point (/31) and host (/32) prefixes have no
+// broadcast address, so this is always false for them. An error is returned
+// if the address is not within the prefix, or the prefix is not IPv4.
+func IsBroadcastAddress(addr netip.Addr, prefix netip.Prefix) (bool, error) {
+	if !prefix.Addr().Is4() {
+		return false, fmt.Errorf("%w: %s", ErrNoBroadcast, prefix)
+	}
+	if !prefix.Contains(addr) {
+		return false, fmt.Errorf("%w: %s is not within %s", ErrAddrNotInPrefix, addr, prefix)
+	}
+	if prefix.Bits() >= 31 {
+		return false, nil
+	}
+	_, end := PrefixToRange(prefix)
+	return addr == end, nil
+}
@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/cidr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsNetworkAddr(t *testing.T) {
+	testCases := []struct {
+		prefix   string
+		expected bool
+	}{
+		{prefix: "10.0.0.0/24", expected: true},
+		{prefix: "10.0.0.5/24", expected: false},
+		{prefix: "10.0.0.4/31", expected: true},
+		{prefix: "10.0.0.5/31", expected: false},
+		{prefix: "10.0.0.5/32", expected: true},
+		{prefix: "0.0.0.0/0", expected: true},
+		{prefix: "10.0.0.0/0", expected: false},
+		{prefix: "fd00::/64", expected: true},
+		{prefix: "fd00::1/64", expected: false},
+		{prefix: "fd00::1/128", expected: true},
+		{prefix: "::/0", expected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.prefix, func(t *testing.T) {
+			prefix := netip.MustParsePrefix(tc.prefix)
+			require.Equal(t, tc.expected, cidr.IsNetworkAddr(prefix))
+
+			err := cidr.RequireNetworkAddr(prefix)
+			if tc.expected {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, cidr.ErrNotNetworkAddr)
+			}
+		})
+	}
+
+	require.False(t, cidr.IsNetworkAddr(netip.Prefix{}))
+}
+
+func TestIsNetworkAddress(t *testing.T) {
+	testCases := []struct {
+		addr     string
+		prefix   string
+		expected bool
+	}{
+		{addr: "10.0.0.0", prefix: "10.0.0.0/24", expected: true},
+		{addr: "10.0.0.1", prefix: "10.0.0.0/24", expected: false},
+		{addr: "10.0.1.0", prefix: "10.0.0.0/24", expected: false},
+		{addr: "10.0.0.0", prefix: "10.0.0.5/24", expected: true},
+		{addr: "10.0.0.4", prefix: "10.0.0.4/31", expected: false},
+		{addr: "10.0.0.4", prefix: "10.0.0.4/32", expected: false},
+		{addr: "fd00::", prefix: "fd00::/64", expected: true},
+		{addr: "fd00::", prefix: "fd00::/127", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.addr+" in "+tc.prefix, func(t *testing.T) {
+			require.Equal(t, tc.expected, cidr.IsNetworkAddress(netip.MustParseAddr(tc.addr), netip.MustParsePrefix(tc.prefix)))
+		})
+	}
+}
+
+func TestIsBroadcastAddress(t *testing.T) {
+	prefix := netip.MustParsePrefix("192.168.1.0/24")
+
+	broadcast, err := cidr.IsBroadcastAddress(netip.MustParseAddr("192.168.1.255"), prefix)
+	require.NoError(t, err)
+	require.True(t, broadcast)
+
+	broadcast, err = cidr.IsBroadcastAddress(netip.MustParseAddr("192.168.1.254"), prefix)
+	require.NoError(t, err)
+	require.False(t, broadcast)
+
+	// Point-to-point and host prefixes have no broadcast address.
+	broadcast, err = cidr.IsBroadcastAddress(netip.MustParseAddr("10.0.0.5"), netip.MustParsePrefix("10.0.0.4/31"))
+	require.NoError(t, err)
+	require.False(t, broadcast)
+
+	broadcast, err = cidr.IsBroadcastAddress(netip.MustParseAddr("10.0.0.5"), netip.MustParsePrefix("10.0.0.5/32"))
+	require.NoError(t, err)
+	require.False(t, broadcast)
+
+	_, err = cidr.IsBroadcastAddress(netip.MustParseAddr("192.168.2.255"), prefix)
+	require.ErrorIs(t, err, cidr.ErrAddrNotInPrefix)
+
+	_, err = cidr.IsBroadcastAddress(netip.MustParseAddr("fd00::ffff"), netip.MustParsePrefix("fd00::/112"))
+	require.ErrorIs(t, err, cidr.ErrNoBroadcast)
+}
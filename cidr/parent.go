@@ -0,0 +1,23 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr
+
+import "net/netip"
+
+// Parent returns the immediate supernet of the prefix, that is the prefix one
+// bit shorter that contains it. It returns false for /0 prefixes, which have
+// no parent, and for invalid prefixes.
+func Parent(prefix netip.Prefix) (netip.Prefix, bool) {
+	if !prefix.IsValid() || prefix.Bits() == 0 {
+		return netip.Prefix{}, false
+	}
+
+	return netip.PrefixFrom(prefix.Addr(), prefix.Bits()-1).Masked(), true
+}
@@ -0,0 +1,46 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/cidr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParent(t *testing.T) {
+	parent, ok := cidr.Parent(netip.MustParsePrefix("10.0.1.0/24"))
+	require.True(t, ok)
+	require.Equal(t, netip.MustParsePrefix("10.0.0.0/23"), parent)
+
+	// Host bits are cleared.
+	parent, ok = cidr.Parent(netip.MustParsePrefix("10.0.1.7/32"))
+	require.True(t, ok)
+	require.Equal(t, netip.MustParsePrefix("10.0.1.6/31"), parent)
+
+	parent, ok = cidr.Parent(netip.MustParsePrefix("2001:db8:8000::/33"))
+	require.True(t, ok)
+	require.Equal(t, netip.MustParsePrefix("2001:db8::/32"), parent)
+
+	parent, ok = cidr.Parent(netip.MustParsePrefix("128.0.0.0/1"))
+	require.True(t, ok)
+	require.Equal(t, netip.MustParsePrefix("0.0.0.0/0"), parent)
+
+	_, ok = cidr.Parent(netip.MustParsePrefix("0.0.0.0/0"))
+	require.False(t, ok)
+
+	_, ok = cidr.Parent(netip.MustParsePrefix("::/0"))
+	require.False(t, ok)
+
+	_, ok = cidr.Parent(netip.Prefix{})
+	require.False(t, ok)
+}
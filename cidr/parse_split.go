@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MaxSplitSubnets is the maximum number of subnets ParseSplit will produce.
+const MaxSplitSubnets = 1 << 16
+
+var (
+	// ErrInvalidSplit is returned when a split spec is malformed.
+	ErrInvalidSplit = errors.New("invalid split spec")
+	// ErrTooManySubnets is returned when a split would produce more than
+	// MaxSplitSubnets subnets.
+	ErrTooManySubnets = errors.New("too many subnets")
+)
+
+// ParseSplit parses a compact subnetting spec and returns the resulting
+// subnets, in ascending order. The grammar is:
+//
+//	spec   = prefix "=>" length
+//	prefix = <network address in CIDR notation, eg. 10.0.0.0/24>
+//	length = [ "/" ] <decimal prefix length>
+//
+// Whitespace around the prefix and length is ignored. For example
+// "10.0.0.0/24=>26" (or "10.0.0.0/24 => /26") splits the /24 into four /26s.
+//
+// The prefix must be a network address (see RequireNetworkAddr) and the length
+// must be longer than the prefix and no longer than the address, otherwise an
+// error wrapping ErrInvalidPrefixLen is returned. Splits producing more than
+// MaxSplitSubnets subnets are rejected with ErrTooManySubnets.
+func ParseSplit(spec string) ([]netip.Prefix, error) {
+	prefixStr, lengthStr, ok := strings.Cut(spec, "=>")
+	if !ok {
+		return nil, fmt.Errorf("%w: %q is missing \"=>\"", ErrInvalidSplit, spec)
+	}
+
+	prefix, err := netip.ParsePrefix(strings.TrimSpace(prefixStr))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSplit, err)
+	}
+	if err := RequireNetworkAddr(prefix); err != nil {
+		return nil, err
+	}
+
+	lengthStr = strings.TrimPrefix(strings.TrimSpace(lengthStr), "/")
+	newBits, err := strconv.Atoi(lengthStr)
+	if err != nil || lengthStr == "" || lengthStr[0] == '+' || lengthStr[0] == '-' {
+		return nil, fmt.Errorf("%w: %q is not a prefix length", ErrInvalidSplit, lengthStr)
+	}
+
+	count, err := SubnetCount(prefix, newBits)
+	if err != nil {
+		return nil, err
+	}
+	if count.Cmp64(MaxSplitSubnets) > 0 {
+		return nil, fmt.Errorf("%w: splitting %s into /%ds would produce %s subnets", ErrTooManySubnets, prefix, newBits, count)
+	}
+
+	return slices.Collect(SubnetsIter(prefix, newBits)), nil
+}
@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/cidr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSplit(t *testing.T) {
+	subnets, err := cidr.ParseSplit("10.0.0.0/24=>26")
+	require.NoError(t, err)
+	require.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/26"),
+		netip.MustParsePrefix("10.0.0.64/26"),
+		netip.MustParsePrefix("10.0.0.128/26"),
+		netip.MustParsePrefix("10.0.0.192/26"),
+	}, subnets)
+
+	subnets, err = cidr.ParseSplit(" 2001:db8::/32 => /33 ")
+	require.NoError(t, err)
+	require.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("2001:db8::/33"),
+		netip.MustParsePrefix("2001:db8:8000::/33"),
+	}, subnets)
+
+	_, err = cidr.ParseSplit("10.0.0.0/24")
+	require.ErrorIs(t, err, cidr.ErrInvalidSplit)
+
+	_, err = cidr.ParseSplit("10.0.0.0/33=>34")
+	require.ErrorIs(t, err, cidr.ErrInvalidSplit)
+
+	_, err = cidr.ParseSplit("10.0.0.0/24=>")
+	require.ErrorIs(t, err, cidr.ErrInvalidSplit)
+
+	_, err = cidr.ParseSplit("10.0.0.0/24=>+26")
+	require.ErrorIs(t, err, cidr.ErrInvalidSplit)
+
+	_, err = cidr.ParseSplit("10.0.0.1/24=>26")
+	require.ErrorIs(t, err, cidr.ErrNotNetworkAddr)
+
+	_, err = cidr.ParseSplit("10.0.0.0/24=>24")
+	require.ErrorIs(t, err, cidr.ErrInvalidPrefixLen)
+
+	_, err = cidr.ParseSplit("10.0.0.0/24=>33")
+	require.ErrorIs(t, err, cidr.ErrInvalidPrefixLen)
+
+	_, err = cidr.ParseSplit("::/0=>64")
+	require.ErrorIs(t, err, cidr.ErrTooManySubnets)
+}
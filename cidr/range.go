@@ -0,0 +1,69 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+
+	"github.com/noisysockets/util/internal/addrbits"
+	"github.com/noisysockets/util/uint128"
+)
+
+var (
+	// ErrInvalidRange is returned when an address range is empty, reversed or
+	// spans address families.
+	ErrInvalidRange = errors.New("invalid address range")
+)
+
+// PrefixToRange returns the first and last addresses in the given prefix.
+func PrefixToRange(prefix netip.Prefix) (start, end netip.Addr) {
+	startVal, totalBits := addrbits.FromAddr(prefix.Masked().Addr())
+	endVal := startVal.Or(addrbits.HostMask(totalBits - prefix.Bits()))
+	return addrbits.ToAddr(startVal, totalBits), addrbits.ToAddr(endVal, totalBits)
+}
+
+// RangeToPrefixes returns the minimal set of prefixes that exactly covers the
+// inclusive address range [start, end], in ascending order.
+func RangeToPrefixes(start, end netip.Addr) ([]netip.Prefix, error) {
+	if !start.IsValid() || !end.IsValid() {
+		return nil, fmt.Errorf("%w: invalid address", ErrInvalidRange)
+	}
+	if start.BitLen() != end.BitLen() {
+		return nil, fmt.Errorf("%w: addresses are of mixed families", ErrInvalidRange)
+	}
+	if end.Less(start) {
+		return nil, fmt.Errorf("%w: end address is before start address", ErrInvalidRange)
+	}
+
+	curr, totalBits := addrbits.FromAddr(start)
+	last, _ := addrbits.FromAddr(end)
+
+	var prefixes []netip.Prefix
+	for {
+		// The largest block that is aligned to the current address.
+		hostBits := min(curr.TrailingZeros(), totalBits)
+
+		// And that doesn't extend past the end of the range.
+		remaining := last.Sub(curr)
+		if remaining != uint128.Max {
+			hostBits = min(hostBits, remaining.Add64(1).Len()-1)
+		}
+
+		prefixes = append(prefixes, netip.PrefixFrom(addrbits.ToAddr(curr, totalBits), totalBits-hostBits))
+
+		blockEnd := curr.Or(addrbits.HostMask(hostBits))
+		if blockEnd == last {
+			return prefixes, nil
+		}
+		curr = blockEnd.Add64(1)
+	}
+}
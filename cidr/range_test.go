@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/cidr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefixToRange(t *testing.T) {
+	start, end := cidr.PrefixToRange(netip.MustParsePrefix("192.168.1.77/24"))
+	require.Equal(t, netip.MustParseAddr("192.168.1.0"), start)
+	require.Equal(t, netip.MustParseAddr("192.168.1.255"), end)
+
+	start, end = cidr.PrefixToRange(netip.MustParsePrefix("fd00::/64"))
+	require.Equal(t, netip.MustParseAddr("fd00::"), start)
+	require.Equal(t, netip.MustParseAddr("fd00::ffff:ffff:ffff:ffff"), end)
+
+	start, end = cidr.PrefixToRange(netip.MustParsePrefix("10.0.0.1/32"))
+	require.Equal(t, netip.MustParseAddr("10.0.0.1"), start)
+	require.Equal(t, netip.MustParseAddr("10.0.0.1"), end)
+}
+
+func TestRangeToPrefixes(t *testing.T) {
+	t.Run("Round Trip", func(t *testing.T) {
+		for _, s := range []string{"10.0.0.0/8", "192.168.1.0/24", "10.0.0.1/32", "0.0.0.0/0", "fd00::/48", "::/0", "fd00::1/128"} {
+			prefix := netip.MustParsePrefix(s)
+
+			prefixes, err := cidr.RangeToPrefixes(cidr.PrefixToRange(prefix))
+			require.NoError(t, err)
+			require.Equal(t, []netip.Prefix{prefix}, prefixes)
+		}
+	})
+
+	t.Run("Unaligned", func(t *testing.T) {
+		prefixes, err := cidr.RangeToPrefixes(netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.0.0.10"))
+		require.NoError(t, err)
+
+		require.Equal(t, []netip.Prefix{
+			netip.MustParsePrefix("10.0.0.1/32"),
+			netip.MustParsePrefix("10.0.0.2/31"),
+			netip.MustParsePrefix("10.0.0.4/30"),
+			netip.MustParsePrefix("10.0.0.8/31"),
+			netip.MustParsePrefix("10.0.0.10/32"),
+		}, prefixes)
+	})
+
+	t.Run("End Of Address Space", func(t *testing.T) {
+		prefixes, err := cidr.RangeToPrefixes(netip.MustParseAddr("255.255.255.254"), netip.MustParseAddr("255.255.255.255"))
+		require.NoError(t, err)
+		require.Equal(t, []netip.Prefix{netip.MustParsePrefix("255.255.255.254/31")}, prefixes)
+
+		prefixes, err = cidr.RangeToPrefixes(netip.MustParseAddr("::1"), netip.MustParseAddr("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"))
+		require.NoError(t, err)
+		require.Len(t, prefixes, 128)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err := cidr.RangeToPrefixes(netip.MustParseAddr("10.0.0.2"), netip.MustParseAddr("10.0.0.1"))
+		require.ErrorIs(t, err, cidr.ErrInvalidRange)
+
+		_, err = cidr.RangeToPrefixes(netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("fd00::1"))
+		require.ErrorIs(t, err, cidr.ErrInvalidRange)
+	})
+}
@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr
+
+import "net/netip"
+
+// PrefixRelation describes how two prefixes relate to one another.
+type PrefixRelation int
+
+const (
+	// RelationDisjoint means the prefixes have no addresses in common.
+	RelationDisjoint PrefixRelation = iota
+	// RelationEqual means the prefixes cover exactly the same addresses.
+	RelationEqual
+	// RelationContains means the first prefix contains the second.
+	RelationContains
+	// RelationContainedBy means the first prefix is contained by the second.
+	RelationContainedBy
+)
+
+func (r PrefixRelation) String() string {
+	switch r {
+	case RelationDisjoint:
+		return "disjoint"
+	case RelationEqual:
+		return "equal"
+	case RelationContains:
+		return "contains"
+	case RelationContainedBy:
+		return "contained by"
+	default:
+		return "unknown"
+	}
+}
+
+// Relation returns the relationship of prefix a to prefix b. Any host bits
+// are ignored, and prefixes of different address families are disjoint.
+func Relation(a, b netip.Prefix) PrefixRelation {
+	a, b = a.Masked(), b.Masked()
+	switch {
+	case !a.IsValid() || !b.IsValid() || !a.Overlaps(b):
+		return RelationDisjoint
+	case a == b:
+		return RelationEqual
+	case a.Bits() < b.Bits():
+		return RelationContains
+	default:
+		return RelationContainedBy
+	}
+}
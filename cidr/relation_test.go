@@ -0,0 +1,44 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/cidr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelation(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected cidr.PrefixRelation
+	}{
+		{a: "10.0.0.0/8", b: "10.0.0.0/8", expected: cidr.RelationEqual},
+		{a: "10.0.0.1/8", b: "10.0.0.0/8", expected: cidr.RelationEqual},
+		{a: "10.0.0.0/8", b: "10.1.0.0/16", expected: cidr.RelationContains},
+		{a: "10.1.0.0/16", b: "10.0.0.0/8", expected: cidr.RelationContainedBy},
+		{a: "10.0.0.0/8", b: "192.168.0.0/16", expected: cidr.RelationDisjoint},
+		{a: "0.0.0.0/0", b: "192.168.0.0/16", expected: cidr.RelationContains},
+		{a: "fd00::/8", b: "fd00:1::/32", expected: cidr.RelationContains},
+		{a: "fd00::/8", b: "fe80::/10", expected: cidr.RelationDisjoint},
+		// Cross family.
+		{a: "0.0.0.0/0", b: "::/0", expected: cidr.RelationDisjoint},
+		{a: "10.0.0.0/8", b: "::ffff:10.0.0.0/104", expected: cidr.RelationDisjoint},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.a+" "+tc.b, func(t *testing.T) {
+			relation := cidr.Relation(netip.MustParsePrefix(tc.a), netip.MustParsePrefix(tc.b))
+			require.Equal(t, tc.expected, relation, "got %s", relation)
+		})
+	}
+}
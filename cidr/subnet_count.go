@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+
+	"github.com/noisysockets/util/uint128"
+)
+
+var (
+	// ErrInvalidPrefixLen is returned when a prefix length is not valid for
+	// the operation or address family.
+	ErrInvalidPrefixLen = errors.New("invalid prefix length")
+)
+
+// SubnetCount returns the number of subnets of length newBits that the given
+// prefix can be divided into, eg. a /16 contains 256 /24s. newBits must be
+// longer than the prefix and no longer than the address. As the count of /128s
+// in ::/0 can't be represented by a uint128, the result saturates at
+// uint128.Max.
+func SubnetCount(prefix netip.Prefix, newBits int) (uint128.Uint128, error) {
+	if !prefix.IsValid() {
+		return uint128.Zero, errors.New("invalid prefix")
+	}
+
+	if newBits <= prefix.Bits() || newBits > prefix.Addr().BitLen() {
+		return uint128.Zero, fmt.Errorf("%w: /%d is not a subnet of %s", ErrInvalidPrefixLen, newBits, prefix)
+	}
+
+	subnetBits := newBits - prefix.Bits()
+	if subnetBits >= 128 {
+		return uint128.Max, nil
+	}
+	return uint128.From64(1).Lsh(uint(subnetBits)), nil
+}
@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/cidr"
+	"github.com/noisysockets/util/uint128"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubnetCount(t *testing.T) {
+	count, err := cidr.SubnetCount(netip.MustParsePrefix("10.0.0.0/16"), 24)
+	require.NoError(t, err)
+	require.Equal(t, uint128.From64(256), count)
+
+	count, err = cidr.SubnetCount(netip.MustParsePrefix("2001:db8::/32"), 48)
+	require.NoError(t, err)
+	require.Equal(t, uint128.From64(65536), count)
+
+	count, err = cidr.SubnetCount(netip.MustParsePrefix("::/0"), 64)
+	require.NoError(t, err)
+	require.Equal(t, uint128.New(0, 1), count)
+
+	count, err = cidr.SubnetCount(netip.MustParsePrefix("::/0"), 128)
+	require.NoError(t, err)
+	require.Equal(t, uint128.Max, count)
+
+	_, err = cidr.SubnetCount(netip.MustParsePrefix("10.0.0.0/16"), 16)
+	require.ErrorIs(t, err, cidr.ErrInvalidPrefixLen)
+
+	_, err = cidr.SubnetCount(netip.MustParsePrefix("10.0.0.0/16"), 33)
+	require.ErrorIs(t, err, cidr.ErrInvalidPrefixLen)
+}
@@ -0,0 +1,38 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr
+
+import (
+	"errors"
+	"math/bits"
+	"net/netip"
+)
+
+var (
+	// ErrSubnetCountOutOfRange is returned when a prefix can't be divided into
+	// the requested number of subnets.
+	ErrSubnetCountOutOfRange = errors.New("subnet count out of range")
+)
+
+// PrefixLenForSubnets returns the smallest prefix length, longer than that of
+// the given prefix, that divides the prefix into at least count subnets. For
+// example, dividing a /16 into 6 subnets requires a prefix length of 19.
+func PrefixLenForSubnets(prefix netip.Prefix, count int) (int, error) {
+	if count < 1 {
+		return 0, ErrSubnetCountOutOfRange
+	}
+
+	newBits := prefix.Bits() + max(bits.Len(uint(count-1)), 1)
+	if newBits > prefix.Addr().BitLen() {
+		return 0, ErrSubnetCountOutOfRange
+	}
+
+	return newBits, nil
+}
@@ -0,0 +1,51 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/cidr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrefixLenForSubnets(t *testing.T) {
+	prefix := netip.MustParsePrefix("10.0.0.0/16")
+
+	testCases := []struct {
+		count    int
+		expected int
+	}{
+		{count: 1, expected: 17},
+		{count: 2, expected: 17},
+		{count: 3, expected: 18},
+		{count: 6, expected: 19},
+		{count: 8, expected: 19},
+		{count: 9, expected: 20},
+		{count: 65536, expected: 32},
+	}
+
+	for _, tc := range testCases {
+		newBits, err := cidr.PrefixLenForSubnets(prefix, tc.count)
+		require.NoError(t, err)
+		require.Equal(t, tc.expected, newBits, "count %d", tc.count)
+	}
+
+	_, err := cidr.PrefixLenForSubnets(prefix, 65537)
+	require.ErrorIs(t, err, cidr.ErrSubnetCountOutOfRange)
+
+	_, err = cidr.PrefixLenForSubnets(prefix, 0)
+	require.ErrorIs(t, err, cidr.ErrSubnetCountOutOfRange)
+
+	newBits, err := cidr.PrefixLenForSubnets(netip.MustParsePrefix("fd00::/48"), 1000)
+	require.NoError(t, err)
+	require.Equal(t, 58, newBits)
+}
@@ -0,0 +1,40 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr
+
+import (
+	"iter"
+	"net/netip"
+
+	"github.com/noisysockets/util/internal/addrbits"
+	"github.com/noisysockets/util/uint128"
+)
+
+// SubnetsIter returns an iterator over the subnets of length newBits that the
+// given prefix can be divided into, in ascending order. Subnets are generated
+// lazily, so it's safe to use on prefixes with an enormous number of subnets
+// as long as the caller stops early. If newBits is shorter than the prefix,
+// or longer than the address, the iterator yields nothing.
+func SubnetsIter(prefix netip.Prefix, newBits int) iter.Seq[netip.Prefix] {
+	return func(yield func(netip.Prefix) bool) {
+		start, totalBits := addrbits.FromAddr(prefix.Masked().Addr())
+		if !prefix.IsValid() || newBits < prefix.Bits() || newBits > totalBits {
+			return
+		}
+
+		step := uint128.From64(1).Lsh(uint(totalBits - newBits))
+		last := start.Or(addrbits.HostMask(totalBits - prefix.Bits()).Xor(addrbits.HostMask(totalBits - newBits)))
+		for curr := start; ; curr = curr.AddWrap(step) {
+			if !yield(netip.PrefixFrom(addrbits.ToAddr(curr, totalBits), newBits)) || curr == last {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr_test
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+
+	"github.com/noisysockets/util/cidr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubnetsIter(t *testing.T) {
+	t.Run("IPv4", func(t *testing.T) {
+		subnets := slices.Collect(cidr.SubnetsIter(netip.MustParsePrefix("10.0.0.0/24"), 26))
+		require.Equal(t, []netip.Prefix{
+			netip.MustParsePrefix("10.0.0.0/26"),
+			netip.MustParsePrefix("10.0.0.64/26"),
+			netip.MustParsePrefix("10.0.0.128/26"),
+			netip.MustParsePrefix("10.0.0.192/26"),
+		}, subnets)
+	})
+
+	t.Run("IPv6", func(t *testing.T) {
+		subnets := slices.Collect(cidr.SubnetsIter(netip.MustParsePrefix("fd00::/63"), 64))
+		require.Equal(t, []netip.Prefix{
+			netip.MustParsePrefix("fd00::/64"),
+			netip.MustParsePrefix("fd00:0:0:1::/64"),
+		}, subnets)
+	})
+
+	t.Run("End Of Address Space", func(t *testing.T) {
+		subnets := slices.Collect(cidr.SubnetsIter(netip.MustParsePrefix("255.255.255.252/30"), 32))
+		require.Len(t, subnets, 4)
+		require.Equal(t, netip.MustParsePrefix("255.255.255.255/32"), subnets[3])
+
+		subnets = slices.Collect(cidr.SubnetsIter(netip.MustParsePrefix("0.0.0.0/0"), 2))
+		require.Equal(t, []netip.Prefix{
+			netip.MustParsePrefix("0.0.0.0/2"),
+			netip.MustParsePrefix("64.0.0.0/2"),
+			netip.MustParsePrefix("128.0.0.0/2"),
+			netip.MustParsePrefix("192.0.0.0/2"),
+		}, subnets)
+	})
+
+	t.Run("Single Subnet", func(t *testing.T) {
+		subnets := slices.Collect(cidr.SubnetsIter(netip.MustParsePrefix("10.0.0.1/24"), 24))
+		require.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/24")}, subnets)
+	})
+
+	t.Run("Early Exit", func(t *testing.T) {
+		var subnets []netip.Prefix
+		for subnet := range cidr.SubnetsIter(netip.MustParsePrefix("::/0"), 128) {
+			subnets = append(subnets, subnet)
+			if len(subnets) == 3 {
+				break
+			}
+		}
+		require.Equal(t, netip.MustParsePrefix("::2/128"), subnets[2])
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		require.Empty(t, slices.Collect(cidr.SubnetsIter(netip.MustParsePrefix("10.0.0.0/24"), 23)))
+		require.Empty(t, slices.Collect(cidr.SubnetsIter(netip.MustParsePrefix("10.0.0.0/24"), 33)))
+	})
+}
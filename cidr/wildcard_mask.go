@@ -0,0 +1,23 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr
+
+import (
+	"net/netip"
+
+	"github.com/noisysockets/util/internal/addrbits"
+)
+
+// WildcardMask returns the wildcard (inverse) mask of the given prefix, eg.
+// 0.0.0.255 for a /24. Wildcard masks are commonly used in Cisco style ACLs.
+func WildcardMask(prefix netip.Prefix) netip.Addr {
+	_, totalBits := addrbits.FromAddr(prefix.Addr())
+	return addrbits.ToAddr(addrbits.HostMask(totalBits-prefix.Bits()), totalBits)
+}
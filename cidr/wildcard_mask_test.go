@@ -0,0 +1,33 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package cidr_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/cidr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWildcardMask(t *testing.T) {
+	t.Run("IPv4", func(t *testing.T) {
+		require.Equal(t, "0.0.0.255", cidr.WildcardMask(netip.MustParsePrefix("192.168.1.0/24")).String())
+		require.Equal(t, "0.0.15.255", cidr.WildcardMask(netip.MustParsePrefix("10.0.0.0/20")).String())
+		require.Equal(t, "0.0.0.0", cidr.WildcardMask(netip.MustParsePrefix("10.0.0.1/32")).String())
+		require.Equal(t, "255.255.255.255", cidr.WildcardMask(netip.MustParsePrefix("0.0.0.0/0")).String())
+	})
+
+	t.Run("IPv6", func(t *testing.T) {
+		require.Equal(t, "::ffff:ffff:ffff:ffff", cidr.WildcardMask(netip.MustParsePrefix("fd00::/64")).String())
+		require.Equal(t, "::", cidr.WildcardMask(netip.MustParsePrefix("fd00::1/128")).String())
+		require.Equal(t, "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", cidr.WildcardMask(netip.MustParsePrefix("::/0")).String())
+	})
+}
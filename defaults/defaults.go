@@ -17,17 +17,46 @@ import (
 )
 
 // WithDefaults populates the provided configuration with its default values.
+// Neither conf nor defaults are ever mutated, both are deep copied so the
+// result is a brand new value that never aliases (and can be freely mutated
+// without affecting) either input.
 func WithDefaults[T any](conf, defaults *T) (*T, error) {
 	var confWithDefaults T
 	if conf != nil {
-		if err := copier.Copy(&confWithDefaults, conf); err != nil {
+		// A deep copy is required as mergo would otherwise merge defaults into
+		// any maps shared with conf.
+		if err := copier.CopyWithOption(&confWithDefaults, conf, copier.Option{DeepCopy: true}); err != nil {
 			return nil, err
 		}
 	}
 
+	if defaults != nil {
+		var defaultsCopy T
+		if err := copier.CopyWithOption(&defaultsCopy, defaults, copier.Option{DeepCopy: true}); err != nil {
+			return nil, err
+		}
+		defaults = &defaultsCopy
+	}
+
 	if err := mergo.Merge(&confWithDefaults, defaults, mergo.WithoutDereference); err != nil {
 		return nil, err
 	}
 
 	return &confWithDefaults, nil
 }
+
+// MustWithDefaults is like WithDefaults but panics if an error occurs.
+func MustWithDefaults[T any](conf, defaults *T) *T {
+	confWithDefaults, err := WithDefaults(conf, defaults)
+	if err != nil {
+		panic(err)
+	}
+	return confWithDefaults
+}
+
+// Merged is a value based variant of MustWithDefaults, it returns a new value
+// with the defaults applied to conf. As the inputs are passed by value, and
+// deep copied, they are never mutated.
+func Merged[T any](conf, defaults T) T {
+	return *MustWithDefaults(&conf, &defaults)
+}
@@ -56,4 +56,88 @@ func TestWithDefaults(t *testing.T) {
 		require.Equal(t, defaultConf.B, conf.B)
 		require.False(t, *conf.C)
 	})
+
+	t.Run("Independent", func(t *testing.T) {
+		a, err := defaults.WithDefaults(&config{}, &defaultConf)
+		require.NoError(t, err)
+
+		b, err := defaults.WithDefaults(&config{}, &defaultConf)
+		require.NoError(t, err)
+
+		a.B[0] = 100
+		*a.C = false
+
+		require.Equal(t, []int{1, 2, 3}, b.B)
+		require.True(t, *b.C)
+
+		require.Equal(t, []int{1, 2, 3}, defaultConf.B)
+		require.True(t, *defaultConf.C)
+	})
+}
+
+func TestMustWithDefaults(t *testing.T) {
+	type config struct {
+		A string
+		B int
+	}
+
+	conf := defaults.MustWithDefaults(&config{A: "set"}, &config{A: "default", B: 1})
+	require.Equal(t, "set", conf.A)
+	require.Equal(t, 1, conf.B)
+
+	require.Panics(t, func() {
+		// Merging into a non-struct type is an error.
+		defaults.MustWithDefaults[int](nil, nil)
+	})
+}
+
+func TestWithDefaultsDoesNotMutate(t *testing.T) {
+	type inner struct {
+		X, Y int
+	}
+
+	type config struct {
+		A string
+		M map[string]int
+		I *inner
+		S []int
+	}
+
+	newConf := func() config {
+		return config{
+			M: map[string]int{"a": 1},
+			I: &inner{X: 1},
+		}
+	}
+
+	newDefaults := func() config {
+		return config{
+			A: "default",
+			M: map[string]int{"b": 2},
+			I: &inner{Y: 2},
+			S: []int{1, 2, 3},
+		}
+	}
+
+	conf, defaultConf := newConf(), newDefaults()
+
+	merged, err := defaults.WithDefaults(&conf, &defaultConf)
+	require.NoError(t, err)
+	require.Equal(t, "default", merged.A)
+
+	require.Equal(t, newConf(), conf)
+	require.Equal(t, newDefaults(), defaultConf)
+
+	// Mutating the result must not affect the inputs.
+	merged.M["c"] = 3
+	merged.I.X = 100
+
+	require.Equal(t, newConf(), conf)
+	require.Equal(t, newDefaults(), defaultConf)
+
+	// The same conf can be reused for multiple merges.
+	again := defaults.Merged(conf, defaultConf)
+	require.Equal(t, newConf(), conf)
+	require.Equal(t, "default", again.A)
+	require.Equal(t, []int{1, 2, 3}, again.S)
 }
@@ -1,6 +1,6 @@
 module github.com/noisysockets/util
 
-go 1.22.4
+go 1.23.0
 
 require (
 	dario.cat/mergo v1.0.0
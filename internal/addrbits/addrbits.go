@@ -0,0 +1,53 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+// Package addrbits converts between netip.Addr and uint128.Uint128 for bit
+// manipulation of addresses, as shared by the cidr and triemap packages.
+package addrbits
+
+import (
+	"encoding/binary"
+	"net/netip"
+
+	"github.com/noisysockets/util/uint128"
+)
+
+// FromAddr converts a netip.Addr into a uint128.Uint128 for easy bit
+// manipulation. It returns the uint128 and the total number of bits for the
+// given address type. Like netip.Prefix.Contains, IPv4-mapped IPv6 addresses
+// are treated as IPv6.
+func FromAddr(addr netip.Addr) (uint128.Uint128, int) {
+	ip6 := addr.As16()
+	if addr.Is4() {
+		return uint128.From64(uint64(binary.BigEndian.Uint32(ip6[12:]))), 32
+	}
+	return uint128.New(binary.BigEndian.Uint64(ip6[8:]), binary.BigEndian.Uint64(ip6[:8])), 128
+}
+
+// ToAddr converts a uint128.Uint128 back into a netip.Addr of the address
+// type with the given total number of bits.
+func ToAddr(u uint128.Uint128, totalBits int) netip.Addr {
+	if totalBits == 32 {
+		var ip4 [4]byte
+		binary.BigEndian.PutUint32(ip4[:], uint32(u.Lo))
+		return netip.AddrFrom4(ip4)
+	}
+	return netip.AddrFrom16(u.BytesBE())
+}
+
+// HostMask returns a mask with the n least significant bits set.
+func HostMask(n int) uint128.Uint128 {
+	return uint128.Max.Rsh(uint(128 - n))
+}
+
+// CommonLen returns the number of leading bits a and b, addresses of
+// totalBits, have in common.
+func CommonLen(a, b uint128.Uint128, totalBits int) int {
+	return min(a.Xor(b).LeadingZeros()-(128-totalBits), totalBits)
+}
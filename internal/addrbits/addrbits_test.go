@@ -0,0 +1,40 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package addrbits_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/internal/addrbits"
+	"github.com/noisysockets/util/uint128"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddrBits(t *testing.T) {
+	for _, s := range []string{"0.0.0.0", "10.1.2.3", "255.255.255.255", "::", "2001:db8::1", "::ffff:10.1.2.3"} {
+		addr := netip.MustParseAddr(s)
+		u, totalBits := addrbits.FromAddr(addr)
+		require.Equal(t, addr.BitLen(), totalBits, s)
+		require.Equal(t, addr, addrbits.ToAddr(u, totalBits), s)
+	}
+
+	u, _ := addrbits.FromAddr(netip.MustParseAddr("10.1.2.3"))
+	require.Equal(t, uint128.From64(0x0a010203), u)
+
+	require.Equal(t, uint128.Zero, addrbits.HostMask(0))
+	require.Equal(t, uint128.From64(0xff), addrbits.HostMask(8))
+	require.Equal(t, uint128.Max, addrbits.HostMask(128))
+
+	a, _ := addrbits.FromAddr(netip.MustParseAddr("10.0.1.1"))
+	b, _ := addrbits.FromAddr(netip.MustParseAddr("10.0.0.0"))
+	require.Equal(t, 23, addrbits.CommonLen(a, b, 32))
+	require.Equal(t, 32, addrbits.CommonLen(a, a, 32))
+}
@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap
+
+import (
+	"maps"
+	"net/netip"
+	"slices"
+)
+
+// Clone returns a deep copy of the TrieMap, configured with the same options.
+// The clone shares no mutable state with the original, so either can be
+// modified without affecting the other. Write-through hooks are not copied.
+func (t *TrieMap[V]) Clone() *TrieMap[V] {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	clone := newWithOptions[V](t.options)
+	t.trieMap.cloneInto(&clone.trieMap)
+	clone.keyToValue = maps.Clone(t.keyToValue)
+	clone.valueToKey = maps.Clone(t.valueToKey)
+	clone.nextKey = t.nextKey
+	return clone
+}
+
+// cloneInto deep copies the trie's nodes and bookkeeping into dst.
+func (t *trieMap) cloneInto(dst *trieMap) {
+	dst.ipv4Root = dst.cloneNode(t.ipv4Root)
+	dst.ipv6Root = dst.cloneNode(t.ipv6Root)
+	dst.keyRefs = maps.Clone(t.keyRefs)
+	if t.keyPrefixes != nil {
+		dst.keyPrefixes = make(map[int][]netip.Prefix, len(t.keyPrefixes))
+		for key, prefixes := range t.keyPrefixes {
+			dst.keyPrefixes[key] = slices.Clone(prefixes)
+		}
+	}
+	dst.disabled = maps.Clone(t.disabled)
+	dst.len = t.len
+}
+
+// cloneNode returns a deep copy of the subtree rooted at node, allocated from
+// this trie.
+func (t *trieMap) cloneNode(node *trieNode) *trieNode {
+	if node == nil {
+		return nil
+	}
+
+	clone := t.newNode(node.addr, node.bits)
+	clone.child0 = t.cloneNode(node.child0)
+	clone.child1 = t.cloneNode(node.child1)
+	if node.value != nil {
+		clone.value = newNodeValue(node.value.prefix, node.value.expires)
+		clone.value.keys = append(clone.value.keys, node.value.keys...)
+	}
+	return clone
+}
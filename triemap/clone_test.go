@@ -0,0 +1,51 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/triemap"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrieMapClone(t *testing.T) {
+	trieMap := triemap.New[string](triemap.WithValueIndex())
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	trieMap.Insert(netip.MustParsePrefix("10.1.0.0/16"), "b")
+	trieMap.Insert(netip.MustParsePrefix("fd00::/8"), "a")
+
+	clone := trieMap.Clone()
+	require.NoError(t, clone.Validate())
+	require.Equal(t, trieMap.ToMap(), clone.ToMap())
+
+	// Removing a prefix from the clone does not affect the original.
+	require.True(t, clone.Remove(netip.MustParsePrefix("10.1.0.0/16")))
+	value, ok := trieMap.Get(netip.MustParseAddr("10.1.2.3"))
+	require.True(t, ok)
+	require.Equal(t, "b", value)
+	require.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.1.0.0/16")}, trieMap.PrefixesFor("b"))
+
+	// And vice versa.
+	require.True(t, trieMap.Remove(netip.MustParsePrefix("fd00::/8")))
+	value, ok = clone.Get(netip.MustParseAddr("fd00::1"))
+	require.True(t, ok)
+	require.Equal(t, "a", value)
+	require.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("fd00::/8")}, clone.PrefixesFor("a"))
+
+	// Inserting into one does not affect the other.
+	clone.Insert(netip.MustParsePrefix("192.168.0.0/16"), "c")
+	_, ok = trieMap.Get(netip.MustParseAddr("192.168.1.1"))
+	require.False(t, ok)
+
+	require.NoError(t, trieMap.Validate())
+	require.NoError(t, clone.Validate())
+}
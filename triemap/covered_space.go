@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap
+
+import (
+	"math/bits"
+
+	"github.com/noisysockets/util/cidr"
+	"github.com/noisysockets/util/uint128"
+)
+
+// CoveredSpace returns the number of distinct IPv4 and IPv6 addresses covered
+// by the union of the prefixes in the TrieMap, so that overlapping prefixes
+// are not counted twice. As the size of ::/0 can't be represented by a
+// uint128, the IPv6 count saturates at uint128.Max.
+//
+// The result is computed on demand by walking the trie under the read lock,
+// only visiting the outermost prefixes, so it is at worst linear in the size
+// of the TrieMap. Disabled prefixes (see SetEnabled) are still counted.
+func (t *TrieMap[V]) CoveredSpace() (v4, v6 uint128.Uint128) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.trieMap.coveredSpace(t.trieMap.ipv4Root), t.trieMap.coveredSpace(t.trieMap.ipv6Root)
+}
+
+// coveredSpace returns the number of addresses covered by the prefixes in the
+// trie rooted at root. Prefixes contained by another prefix are skipped, as
+// the outermost prefixes of a trie are always disjoint.
+func (t *trieMap) coveredSpace(root *trieNode) uint128.Uint128 {
+	var total uint128.Uint128
+	if root == nil {
+		return total
+	}
+
+	stack := []*trieNode{root}
+	for len(stack) > 0 {
+		curr := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if curr.value != nil {
+			total = addSaturating(total, cidr.HostCount(curr.value.prefix))
+			continue
+		}
+		if curr.child0 != nil {
+			stack = append(stack, curr.child0)
+		}
+		if curr.child1 != nil {
+			stack = append(stack, curr.child1)
+		}
+	}
+	return total
+}
+
+// addSaturating returns a+b, saturating at uint128.Max rather than overflowing.
+func addSaturating(a, b uint128.Uint128) uint128.Uint128 {
+	lo, carry := bits.Add64(a.Lo, b.Lo, 0)
+	hi, carry := bits.Add64(a.Hi, b.Hi, carry)
+	if carry != 0 {
+		return uint128.Max
+	}
+	return uint128.New(lo, hi)
+}
@@ -0,0 +1,48 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/triemap"
+	"github.com/noisysockets/util/uint128"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrieMapCoveredSpace(t *testing.T) {
+	trieMap := triemap.New[string]()
+
+	v4, v6 := trieMap.CoveredSpace()
+	require.True(t, v4.IsZero())
+	require.True(t, v6.IsZero())
+
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	// Contained by 10.0.0.0/8, so it's not counted twice.
+	trieMap.Insert(netip.MustParsePrefix("10.1.0.0/16"), "b")
+	trieMap.Insert(netip.MustParsePrefix("192.168.1.0/24"), "c")
+	trieMap.Insert(netip.MustParsePrefix("192.168.2.1/32"), "d")
+	trieMap.Insert(netip.MustParsePrefix("2001:db8::/32"), "e")
+	trieMap.Insert(netip.MustParsePrefix("2001:db8:1::/48"), "f")
+	trieMap.Insert(netip.MustParsePrefix("fd00::/64"), "g")
+
+	v4, v6 = trieMap.CoveredSpace()
+	require.Equal(t, uint128.From64(1<<24+256+1), v4)
+	require.Equal(t, uint128.From64(1).Lsh(96).Add(uint128.From64(1).Lsh(64)), v6)
+
+	// The default routes cover the whole address space.
+	trieMap.Insert(netip.MustParsePrefix("0.0.0.0/0"), "h")
+	trieMap.Insert(netip.MustParsePrefix("::/0"), "i")
+
+	v4, v6 = trieMap.CoveredSpace()
+	require.Equal(t, uint128.From64(1<<32), v4)
+	require.Equal(t, uint128.Max, v6)
+}
@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap
+
+import "net/netip"
+
+// DiffTo returns the minimal set of changes required to transform the TrieMap
+// into target. Applying toRemove with Remove and then toInsert with Insert
+// will result in a TrieMap with the same entries as target.
+//
+// Entries are compared by exact prefix and value. A prefix present in both
+// maps but with a different value is only reported in toInsert, as inserting
+// it will overwrite the existing value. Only the primary value of each prefix
+// is considered (see InsertWeighted).
+//
+// The target is snapshotted before the TrieMap is compared against it, so the
+// result is not atomic with respect to concurrent mutations of both maps.
+func (t *TrieMap[V]) DiffTo(target *TrieMap[V]) (toInsert []Entry[V], toRemove []netip.Prefix) {
+	if t == target {
+		return nil, nil
+	}
+
+	targetEntries := target.entries()
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	targetPrefixes := make(map[netip.Prefix]struct{}, len(targetEntries))
+	for _, entry := range targetEntries {
+		targetPrefixes[entry.Prefix.Masked()] = struct{}{}
+
+		value := t.trieMap.find(entry.Prefix)
+		if value == nil || t.primary(value) != entry.Value {
+			toInsert = append(toInsert, entry)
+		}
+	}
+
+	t.trieMap.walk(func(value *nodeValue) bool {
+		if _, ok := targetPrefixes[value.prefix.Masked()]; !ok {
+			toRemove = append(toRemove, value.prefix)
+		}
+		return true
+	})
+
+	return toInsert, toRemove
+}
+
+// entries returns a snapshot of all entries (with their primary values) in the
+// TrieMap.
+func (t *TrieMap[V]) entries() []Entry[V] {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var entries []Entry[V]
+	t.trieMap.walk(func(value *nodeValue) bool {
+		entries = append(entries, Entry[V]{Prefix: value.prefix, Value: t.primary(value)})
+		return true
+	})
+	return entries
+}
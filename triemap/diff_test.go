@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/triemap"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrieMapDiffTo(t *testing.T) {
+	source := triemap.New[string]()
+	source.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	source.Insert(netip.MustParsePrefix("10.1.0.0/16"), "b")
+	source.Insert(netip.MustParsePrefix("192.168.0.0/16"), "c")
+	source.Insert(netip.MustParsePrefix("fd00::/8"), "x")
+
+	target := triemap.New[string]()
+	target.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	target.Insert(netip.MustParsePrefix("10.1.0.0/16"), "changed")
+	target.Insert(netip.MustParsePrefix("172.16.0.0/12"), "e")
+	target.Insert(netip.MustParsePrefix("fd00::/16"), "d")
+
+	toInsert, toRemove := source.DiffTo(target)
+
+	require.Equal(t, []triemap.Entry[string]{
+		{Prefix: netip.MustParsePrefix("10.1.0.0/16"), Value: "changed"},
+		{Prefix: netip.MustParsePrefix("172.16.0.0/12"), Value: "e"},
+		{Prefix: netip.MustParsePrefix("fd00::/16"), Value: "d"},
+	}, toInsert)
+
+	require.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("192.168.0.0/16"),
+		netip.MustParsePrefix("fd00::/8"),
+	}, toRemove)
+
+	// Applying the diff should result in no further differences.
+	for _, prefix := range toRemove {
+		require.True(t, source.Remove(prefix))
+	}
+	for _, entry := range toInsert {
+		source.Insert(entry.Prefix, entry.Value)
+	}
+
+	toInsert, toRemove = source.DiffTo(target)
+	require.Empty(t, toInsert)
+	require.Empty(t, toRemove)
+
+	value, contains := source.Get(netip.MustParseAddr("10.1.2.3"))
+	require.True(t, contains)
+	require.Equal(t, "changed", value)
+}
@@ -0,0 +1,128 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"net/netip"
+)
+
+// MarshalJSON encodes the TrieMap as a JSON array of prefix and value pairs,
+// in ascending prefix order, eg. [{"prefix":"10.0.0.0/8","value":"a"}]. A
+// prefix with multiple values (see WithMultimap and InsertWeighted) is encoded
+// as one pair per value, in the order the values were inserted.
+//
+// Values are encoded with encoding/json, so V must be JSON marshalable.
+// Weights, TTLs and disabled states are not encoded.
+func (t *TrieMap[V]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.allEntries())
+}
+
+// UnmarshalJSON replaces the contents of the TrieMap with the prefix and value
+// pairs encoded by MarshalJSON. The TrieMap keeps the options it was created
+// with, a zero TrieMap is populated with the default options.
+func (t *TrieMap[V]) UnmarshalJSON(data []byte) error {
+	var entries []Entry[V]
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	inserted, removed := t.load(entries)
+	t.mu.Unlock()
+
+	t.notifyAll(inserted, removed)
+	return nil
+}
+
+// GobEncode encodes the TrieMap's prefix and value pairs with encoding/gob,
+// like MarshalJSON. V must be encodable by encoding/gob. Weights, TTLs and
+// disabled states are not encoded.
+func (t *TrieMap[V]) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(t.allEntries()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GobDecode replaces the contents of the TrieMap with the prefix and value
+// pairs encoded by GobEncode, like UnmarshalJSON.
+func (t *TrieMap[V]) GobDecode(data []byte) error {
+	var entries []Entry[V]
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entries); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	inserted, removed := t.load(entries)
+	t.mu.Unlock()
+
+	t.notifyAll(inserted, removed)
+	return nil
+}
+
+// allEntries returns a snapshot of every prefix and value pair in the
+// TrieMap, in ascending prefix order. Unlike entries, every value of a prefix
+// is included rather than just the primary value.
+func (t *TrieMap[V]) allEntries() []Entry[V] {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	entries := make([]Entry[V], 0, t.trieMap.len)
+	t.trieMap.walk(func(value *nodeValue) bool {
+		for _, k := range value.keys {
+			entries = append(entries, Entry[V]{Prefix: value.prefix, Value: t.keyToValue[k.key]})
+		}
+		return true
+	})
+	return entries
+}
+
+// load replaces the contents of the TrieMap with the given prefix and value
+// pairs. Every value of a repeated prefix is kept, as by InsertWeighted. If
+// write through hooks are set, the changes to report are returned. The caller
+// must hold the write lock.
+func (t *TrieMap[V]) load(entries []Entry[V]) (inserted []Entry[V], removed []netip.Prefix) {
+	hooked := t.hooked()
+
+	var prefixes []netip.Prefix
+	if hooked {
+		t.trieMap.walk(func(value *nodeValue) bool {
+			prefixes = append(prefixes, value.prefix)
+			return true
+		})
+	}
+
+	t.trieMap.clear()
+	t.keyToValue = make(map[int]V)
+	t.valueToKey = make(map[V]int)
+
+	for _, e := range entries {
+		if !e.Prefix.IsValid() {
+			continue
+		}
+		t.trieMap.insertWeighted(e.Prefix, t.keyFor(e.Value), 1)
+	}
+
+	if !hooked {
+		return nil, nil
+	}
+
+	// Prefixes that are still present are reported as inserted below.
+	_, removed = t.changes(prefixes)
+	t.trieMap.walk(func(value *nodeValue) bool {
+		inserted = append(inserted, Entry[V]{Prefix: value.prefix, Value: t.primary(value)})
+		return true
+	})
+	return inserted, removed
+}
@@ -0,0 +1,104 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/triemap"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrieMapJSON(t *testing.T) {
+	trieMap := triemap.New[string]()
+	for value, prefixes := range testPrefixes {
+		for _, prefix := range prefixes {
+			trieMap.Insert(prefix, value)
+		}
+	}
+
+	data, err := json.Marshal(trieMap)
+	require.NoError(t, err)
+
+	var decoded triemap.TrieMap[string]
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.NoError(t, decoded.Validate())
+	require.Equal(t, trieMap.ToMap(), decoded.ToMap())
+
+	// Longest prefix matching behaves the same.
+	for _, tc := range testCases {
+		expectedValue, expectedOK := trieMap.Get(tc.Addr)
+		value, ok := decoded.Get(tc.Addr)
+		require.Equal(t, expectedOK, ok, tc.Addr)
+		require.Equal(t, expectedValue, value, tc.Addr)
+	}
+}
+
+func TestTrieMapJSONFormat(t *testing.T) {
+	trieMap := triemap.New[string](triemap.WithMultimap())
+	trieMap.Insert(netip.MustParsePrefix("fd00::/8"), "c")
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "b")
+
+	data, err := json.Marshal(trieMap)
+	require.NoError(t, err)
+	require.JSONEq(t, `[
+		{"prefix": "10.0.0.0/8", "value": "a"},
+		{"prefix": "10.0.0.0/8", "value": "b"},
+		{"prefix": "fd00::/8", "value": "c"}
+	]`, string(data))
+
+	// Unmarshaling replaces the existing contents, and keeps every value of a
+	// repeated prefix.
+	decoded := triemap.New[string](triemap.WithValueIndex())
+	decoded.Insert(netip.MustParsePrefix("192.168.0.0/16"), "d")
+	require.NoError(t, json.Unmarshal(data, decoded))
+	require.NoError(t, decoded.Validate())
+	require.Equal(t, 2, decoded.Len())
+	require.Equal(t, []string{"a", "b"}, decoded.GetValues(netip.MustParseAddr("10.1.2.3")))
+	require.Empty(t, decoded.PrefixesFor("d"))
+
+	require.Error(t, json.Unmarshal([]byte(`[{"prefix": "10.0.0.0/33"}]`), decoded))
+}
+
+func TestTrieMapGob(t *testing.T) {
+	trieMap := triemap.New[string]()
+	for value, prefixes := range testPrefixes {
+		for _, prefix := range prefixes {
+			trieMap.Insert(prefix, value)
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, gob.NewEncoder(&buf).Encode(trieMap))
+
+	decoded := triemap.New[string]()
+	decoded.Insert(netip.MustParsePrefix("192.168.0.0/16"), "stale")
+	require.NoError(t, gob.NewDecoder(&buf).Decode(decoded))
+	require.NoError(t, decoded.Validate())
+	require.Equal(t, trieMap.ToMap(), decoded.ToMap())
+
+	// Longest prefix matching behaves the same, for both address families.
+	for _, tc := range testCases {
+		expectedValue, expectedOK := trieMap.Get(tc.Addr)
+		value, ok := decoded.Get(tc.Addr)
+		require.Equal(t, expectedOK, ok, tc.Addr)
+		require.Equal(t, expectedValue, value, tc.Addr)
+	}
+
+	// The decoded map is fully usable.
+	decoded.Insert(netip.MustParsePrefix("fd00::/8"), "ula")
+	require.True(t, decoded.Remove(netip.MustParsePrefix("fd00::/8")))
+	require.NoError(t, decoded.Validate())
+}
@@ -0,0 +1,69 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap
+
+import "unsafe"
+
+// Equal returns true if both TrieMaps contain exactly the same prefix and
+// value pairs. Prefixes with multiple values (see WithMultimap and
+// InsertWeighted) are equal if they hold the same set of values, regardless
+// of the order in which they were inserted. Weights, TTLs and disabled states
+// are not compared.
+func (t *TrieMap[V]) Equal(other *TrieMap[V]) bool {
+	if t == other {
+		return true
+	}
+
+	// Always lock the maps in the same order, so that concurrent calls
+	// comparing the same maps can't deadlock.
+	first, second := t, other
+	if uintptr(unsafe.Pointer(second)) < uintptr(unsafe.Pointer(first)) {
+		first, second = second, first
+	}
+	first.mu.RLock()
+	defer first.mu.RUnlock()
+	second.mu.RLock()
+	defer second.mu.RUnlock()
+
+	if t.trieMap.len != other.trieMap.len {
+		return false
+	}
+
+	pairs := make(map[Entry[V]]int)
+	t.trieMap.walk(func(value *nodeValue) bool {
+		for _, k := range value.keys {
+			pairs[Entry[V]{Prefix: value.prefix, Value: t.keyToValue[k.key]}]++
+		}
+		return true
+	})
+
+	equal := true
+	other.trieMap.walk(func(value *nodeValue) bool {
+		for _, k := range value.keys {
+			pair := Entry[V]{Prefix: value.prefix, Value: other.keyToValue[k.key]}
+			if pairs[pair] == 0 {
+				equal = false
+				return false
+			}
+			pairs[pair]--
+		}
+		return true
+	})
+	if !equal {
+		return false
+	}
+
+	for _, n := range pairs {
+		if n != 0 {
+			return false
+		}
+	}
+	return true
+}
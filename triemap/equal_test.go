@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/triemap"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrieMapEqual(t *testing.T) {
+	a := triemap.New[string]()
+	a.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	a.Insert(netip.MustParsePrefix("10.1.0.0/16"), "b")
+	a.Insert(netip.MustParsePrefix("fd00::/8"), "a")
+
+	// The same entries, built differently so that the internal reference
+	// counts and keys differ.
+	b := triemap.New[string]()
+	b.Insert(netip.MustParsePrefix("192.168.0.0/16"), "a")
+	b.Insert(netip.MustParsePrefix("fd00::/8"), "a")
+	b.Insert(netip.MustParsePrefix("10.1.0.0/16"), "c")
+	b.Insert(netip.MustParsePrefix("10.1.0.0/16"), "b")
+	b.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	b.Remove(netip.MustParsePrefix("192.168.0.0/16"))
+
+	require.True(t, a.Equal(b))
+	require.True(t, b.Equal(a))
+	require.True(t, a.Equal(a))
+
+	b.Insert(netip.MustParsePrefix("10.1.0.0/16"), "c")
+	require.False(t, a.Equal(b))
+
+	b.Insert(netip.MustParsePrefix("10.1.0.0/16"), "b")
+	b.Insert(netip.MustParsePrefix("10.2.0.0/16"), "b")
+	require.False(t, a.Equal(b))
+	require.False(t, b.Equal(a))
+
+	require.True(t, triemap.New[string]().Equal(triemap.New[string]()))
+}
+
+func TestTrieMapEqualMultimap(t *testing.T) {
+	a := triemap.New[string](triemap.WithMultimap())
+	a.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	a.Insert(netip.MustParsePrefix("10.0.0.0/8"), "b")
+
+	// The order of values doesn't matter.
+	b := triemap.New[string](triemap.WithMultimap())
+	b.Insert(netip.MustParsePrefix("10.0.0.0/8"), "b")
+	b.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	require.True(t, a.Equal(b))
+
+	b.Insert(netip.MustParsePrefix("10.0.0.0/8"), "c")
+	require.False(t, a.Equal(b))
+	require.False(t, b.Equal(a))
+}
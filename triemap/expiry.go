@@ -0,0 +1,111 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap
+
+import (
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// InsertTTL inserts value into the TrieMap by prefix, like Insert, but the
+// prefix expires once ttl has elapsed. Expired prefixes remain visible until
+// they are removed by ExpireNow (or a sweeper started by StartExpiry). A
+// subsequent Insert for the same prefix clears the expiry.
+func (t *TrieMap[V]) InsertTTL(prefix netip.Prefix, value V, ttl time.Duration) {
+	if !prefix.IsValid() {
+		return
+	}
+
+	t.mu.Lock()
+	key := t.keyFor(value)
+	t.insert(prefix, key)
+	t.trieMap.find(prefix).expires = time.Now().Add(ttl)
+	t.mu.Unlock()
+
+	t.notifyInsert(prefix, value)
+}
+
+// ExpireNow removes all prefixes whose TTL has elapsed, returning the number
+// of prefixes removed.
+//
+// Expired prefixes are found under the read lock, so the write lock is only
+// held for as long as it takes to remove them.
+func (t *TrieMap[V]) ExpireNow() int {
+	now := time.Now()
+
+	var expired []netip.Prefix
+	t.mu.RLock()
+	t.trieMap.walk(func(value *nodeValue) bool {
+		if isExpired(value, now) {
+			expired = append(expired, value.prefix)
+		}
+		return true
+	})
+	t.mu.RUnlock()
+
+	if len(expired) == 0 {
+		return 0
+	}
+
+	t.mu.Lock()
+	removed := expired[:0]
+	for _, prefix := range expired {
+		// The prefix may have been replaced since it was found.
+		if value := t.trieMap.find(prefix); value != nil && isExpired(value, now) {
+			t.remove(prefix)
+			removed = append(removed, prefix)
+		}
+	}
+	t.mu.Unlock()
+
+	t.notifyAll(nil, removed)
+	return len(removed)
+}
+
+// StartExpiry starts a background goroutine that calls ExpireNow every
+// interval. The returned stop function stops the goroutine and waits for it
+// to exit, it is safe to call more than once. If interval is not positive, no
+// goroutine is started and stop does nothing.
+func (t *TrieMap[V]) StartExpiry(interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				t.ExpireNow()
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-stopped
+	}
+}
+
+// isExpired returns true if the value has an expiry that has elapsed.
+func isExpired(value *nodeValue, now time.Time) bool {
+	return !value.expires.IsZero() && !now.Before(value.expires)
+}
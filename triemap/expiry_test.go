@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap_test
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/noisysockets/util/triemap"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrieMapExpireNow(t *testing.T) {
+	trieMap := triemap.New[string]()
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	trieMap.InsertTTL(netip.MustParsePrefix("10.1.0.0/16"), "b", 0)
+	trieMap.InsertTTL(netip.MustParsePrefix("10.2.0.0/16"), "c", time.Hour)
+	trieMap.InsertTTL(netip.MustParsePrefix("10.3.0.0/16"), "d", 0)
+
+	// A subsequent insert clears the expiry.
+	trieMap.Insert(netip.MustParsePrefix("10.3.0.0/16"), "d")
+
+	// Expired prefixes are visible until they are removed.
+	value, ok := trieMap.Get(netip.MustParseAddr("10.1.0.1"))
+	require.True(t, ok)
+	require.Equal(t, "b", value)
+
+	require.Equal(t, 1, trieMap.ExpireNow())
+	require.Zero(t, trieMap.ExpireNow())
+
+	value, ok = trieMap.Get(netip.MustParseAddr("10.1.0.1"))
+	require.True(t, ok)
+	require.Equal(t, "a", value)
+
+	for addr, expected := range map[string]string{
+		"10.2.0.1": "c",
+		"10.3.0.1": "d",
+	} {
+		value, ok := trieMap.Get(netip.MustParseAddr(addr))
+		require.True(t, ok)
+		require.Equal(t, expected, value)
+	}
+}
+
+func TestTrieMapStartExpiry(t *testing.T) {
+	trieMap := triemap.New[string]()
+	trieMap.InsertTTL(netip.MustParsePrefix("10.0.0.0/8"), "a", 10*time.Millisecond)
+
+	stop := trieMap.StartExpiry(time.Millisecond)
+	t.Cleanup(stop)
+
+	require.Eventually(t, trieMap.Empty, time.Second, time.Millisecond)
+
+	// Stopping more than once is safe.
+	stop()
+	stop()
+
+	// A non-positive interval doesn't start a sweeper.
+	trieMap.InsertTTL(netip.MustParsePrefix("10.0.0.0/8"), "a", 0)
+	for _, interval := range []time.Duration{0, -time.Second} {
+		stop = trieMap.StartExpiry(interval)
+		stop()
+		stop()
+	}
+	require.Equal(t, 1, trieMap.Len())
+}
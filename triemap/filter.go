@@ -0,0 +1,132 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap
+
+import (
+	"encoding/binary"
+	"net/netip"
+)
+
+const (
+	// Covered prefixes are summarized by the /16 (IPv4) or /32 (IPv6) blocks
+	// that they overlap.
+	filterBlockBits4 = 16
+	filterBlockBits6 = 32
+	// Sizing of the bloom filter, 10 bits per block and 3 hashes gives a false
+	// positive rate of roughly 1.7%.
+	filterBitsPerBlock = 10
+	filterHashes       = 3
+)
+
+// AddrFilter is a compact, probabilistic summary of the addresses covered by
+// the prefixes of a TrieMap, see MembershipFilter. It is immutable and safe
+// for concurrent use.
+type AddrFilter struct {
+	// short holds prefixes that are shorter than a block, they are checked
+	// exactly.
+	short []netip.Prefix
+	// bits is a bloom filter of the blocks overlapped by longer prefixes.
+	bits []uint64
+}
+
+// MembershipFilter returns a compact summary of the addresses covered by the
+// TrieMap, that can be used as a cheap pre-check before a full lookup (eg. in
+// a remote shard). The filter is a snapshot, it does not reflect subsequent
+// mutations of the TrieMap.
+//
+// The filter never has false negatives, if Get would match an address then
+// MayContain returns true. It does however have false positives, any address
+// that shares a /16 (IPv4) or /32 (IPv6) block with a covered prefix may be
+// reported, as may roughly 1.7% of other addresses due to hash collisions.
+func (t *TrieMap[V]) MembershipFilter() *AddrFilter {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var f AddrFilter
+	var blocks []uint64
+	t.trieMap.walk(func(value *nodeValue) bool {
+		prefix := value.prefix.Masked()
+		if prefix.Bits() < filterBlockBits(prefix.Addr()) {
+			f.short = append(f.short, prefix)
+		} else {
+			blocks = append(blocks, filterBlock(prefix.Addr()))
+		}
+		return true
+	})
+
+	f.bits = make([]uint64, len(blocks)*filterBitsPerBlock/64+1)
+	for _, block := range blocks {
+		h1, h2 := filterHash(block)
+		for i := uint64(0); i < filterHashes; i++ {
+			n := (h1 + i*h2) % uint64(len(f.bits)*64)
+			f.bits[n/64] |= 1 << (n % 64)
+		}
+	}
+
+	return &f
+}
+
+// MayContain returns false if the address is definitely not covered by any
+// prefix, or true if it may be. Like TrieMap.Get, any zone is ignored.
+func (f *AddrFilter) MayContain(addr netip.Addr) bool {
+	addr = addr.WithZone("")
+	for _, prefix := range f.short {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	if !addr.IsValid() {
+		return false
+	}
+
+	h1, h2 := filterHash(filterBlock(addr))
+	for i := uint64(0); i < filterHashes; i++ {
+		n := (h1 + i*h2) % uint64(len(f.bits)*64)
+		if f.bits[n/64]&(1<<(n%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// filterBlockBits returns the length of the filter blocks for the family of
+// the address.
+func filterBlockBits(addr netip.Addr) int {
+	if addr.Is4() {
+		return filterBlockBits4
+	}
+	return filterBlockBits6
+}
+
+// filterBlock returns an identifier of the block containing the address.
+func filterBlock(addr netip.Addr) uint64 {
+	if addr.Is4() {
+		ip4 := addr.As4()
+		return 4<<32 | uint64(binary.BigEndian.Uint16(ip4[:2]))
+	}
+	ip6 := addr.As16()
+	return 6<<32 | uint64(binary.BigEndian.Uint32(ip6[:4]))
+}
+
+// filterHash derives a pair of hashes of the block, for double hashing.
+func filterHash(block uint64) (uint64, uint64) {
+	h1 := splitmix64(block)
+	return h1, splitmix64(h1) | 1
+}
+
+// splitmix64 is the finalizer of the SplitMix64 generator, a fast and well
+// distributed 64-bit mixing function.
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
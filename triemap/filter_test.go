@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap_test
+
+import (
+	"encoding/binary"
+	"math/rand/v2"
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/triemap"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrieMapMembershipFilter(t *testing.T) {
+	trieMap := triemap.New[int]()
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), 0)
+	trieMap.Insert(netip.MustParsePrefix("fd00::/7"), 0)
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < 1000; i++ {
+		var ip4 [4]byte
+		binary.BigEndian.PutUint32(ip4[:], rng.Uint32())
+		trieMap.Insert(netip.PrefixFrom(netip.AddrFrom4(ip4), 24+rng.IntN(9)).Masked(), i)
+	}
+
+	filter := trieMap.MembershipFilter()
+
+	// No false negatives.
+	trieMap.Walk(func(prefix netip.Prefix, _ int) bool {
+		require.True(t, filter.MayContain(prefix.Addr()), prefix)
+		return true
+	})
+	require.True(t, filter.MayContain(netip.MustParseAddr("10.1.2.3")))
+	require.True(t, filter.MayContain(netip.MustParseAddr("fd12::1")))
+
+	// Zones are ignored, as they are by Get.
+	_, ok := trieMap.Get(netip.MustParseAddr("fd12::1%eth0"))
+	require.True(t, ok)
+	require.True(t, filter.MayContain(netip.MustParseAddr("fd12::1%eth0")))
+
+	// And a reasonable false positive rate.
+	var falsePositives, misses int
+	for i := 0; i < 10000; i++ {
+		var ip4 [4]byte
+		binary.BigEndian.PutUint32(ip4[:], rng.Uint32())
+		addr := netip.AddrFrom4(ip4)
+
+		if _, ok := trieMap.Get(addr); ok {
+			continue
+		}
+		misses++
+		if filter.MayContain(addr) {
+			falsePositives++
+		}
+	}
+	require.Less(t, float64(falsePositives)/float64(misses), 0.05)
+
+	require.False(t, triemap.New[int]().MembershipFilter().MayContain(netip.MustParseAddr("10.0.0.1")))
+}
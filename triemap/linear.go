@@ -0,0 +1,92 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap
+
+import (
+	"net/netip"
+	"sync"
+)
+
+// LinearMap is a minimal map of netip.Prefix to values of type V, that
+// matches a netip.Addr to the longest matching prefix with a linear scan.
+//
+// It serves as a reference implementation of TrieMap, and Get has identical
+// semantics, but it is also a reasonable choice for tiny tables that don't
+// warrant a trie. It is safe for concurrent use.
+type LinearMap[V comparable] struct {
+	mu      sync.RWMutex
+	entries []Entry[V]
+}
+
+// NewLinearMap returns a new, empty LinearMap[V].
+func NewLinearMap[V comparable]() *LinearMap[V] {
+	return &LinearMap[V]{}
+}
+
+// Insert inserts value into the LinearMap by prefix, replacing any existing
+// value for the prefix. Prefixes are normalized to their masked form, and
+// invalid prefixes are ignored.
+func (m *LinearMap[V]) Insert(prefix netip.Prefix, value V) {
+	if !prefix.IsValid() {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prefix = prefix.Masked()
+	for i := range m.entries {
+		if m.entries[i].Prefix == prefix {
+			m.entries[i].Value = value
+			return
+		}
+	}
+	m.entries = append(m.entries, Entry[V]{Prefix: prefix, Value: value})
+}
+
+// Get returns the value for the longest prefix containing addr if any with
+// contains=true, or else the default value of V and contains=false.
+func (m *LinearMap[V]) Get(addr netip.Addr) (value V, contains bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	longestMatchLength := -1
+	for _, entry := range m.entries {
+		if entry.Prefix.Bits() > longestMatchLength && entry.Prefix.Contains(addr) {
+			longestMatchLength = entry.Prefix.Bits()
+			value, contains = entry.Value, true
+		}
+	}
+	return
+}
+
+// Remove removes the prefix from the LinearMap. Returns true if the prefix
+// was removed, false if it was not found.
+func (m *LinearMap[V]) Remove(prefix netip.Prefix) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	prefix = prefix.Masked()
+	for i := range m.entries {
+		if m.entries[i].Prefix == prefix {
+			m.entries = append(m.entries[:i], m.entries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of prefixes in the LinearMap.
+func (m *LinearMap[V]) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.entries)
+}
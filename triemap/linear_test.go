@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/triemap"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinearMap(t *testing.T) {
+	linearMap := triemap.NewLinearMap[string]()
+	linearMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	linearMap.Insert(netip.MustParsePrefix("10.1.2.3/16"), "b")
+	linearMap.Insert(netip.MustParsePrefix("fd00::/8"), "c")
+	linearMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "d")
+
+	require.Equal(t, 3, linearMap.Len())
+
+	value, ok := linearMap.Get(netip.MustParseAddr("10.1.0.1"))
+	require.True(t, ok)
+	require.Equal(t, "b", value)
+
+	value, ok = linearMap.Get(netip.MustParseAddr("10.2.0.1"))
+	require.True(t, ok)
+	require.Equal(t, "d", value)
+
+	_, ok = linearMap.Get(netip.MustParseAddr("192.168.1.1"))
+	require.False(t, ok)
+
+	require.True(t, linearMap.Remove(netip.MustParsePrefix("10.1.0.0/16")))
+	require.False(t, linearMap.Remove(netip.MustParsePrefix("10.1.0.0/16")))
+
+	value, ok = linearMap.Get(netip.MustParseAddr("10.1.0.1"))
+	require.True(t, ok)
+	require.Equal(t, "d", value)
+}
+
+// FuzzTrieMapParity applies a random sequence of operations to both a TrieMap
+// and a LinearMap and checks they always agree.
+func FuzzTrieMapParity(f *testing.F) {
+	f.Add([]byte{0, 4, 10, 0, 0, 0, 8, 1, 2, 4, 10, 1, 2, 3, 0, 0})
+	f.Add([]byte{0, 4, 10, 0, 0, 0, 8, 1, 1, 4, 10, 0, 0, 0, 8, 0, 0, 4, 10, 0, 0, 0, 8, 1, 2, 4, 10, 0, 0, 1, 0, 0})
+	f.Add([]byte{0, 1, 0xfd, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 8, 3, 2, 1, 0xfd, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0})
+	f.Add([]byte{0, 4, 0, 0, 0, 0, 0, 1, 0, 4, 192, 168, 1, 0, 24, 2, 1, 4, 0, 0, 0, 0, 0, 0, 0, 4, 192, 168, 1, 0, 24, 1, 2, 4, 8, 8, 8, 8, 0, 0})
+	// IPv4-mapped addresses and prefixes.
+	f.Add([]byte{
+		0, 4, 0, 0, 0, 0, 0, 1,
+		2, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 1, 2, 3, 4, 0, 0,
+		0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 10, 0, 0, 0, 104, 2,
+		2, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 10, 1, 2, 3, 0, 0,
+		2, 4, 10, 1, 2, 3, 0, 0,
+		1, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 10, 0, 0, 0, 104, 0,
+		2, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 10, 1, 2, 3, 0, 0,
+	})
+	// Invalid (zero) addresses and invalid prefix lengths.
+	f.Add([]byte{
+		0, 2, 0, 1,
+		2, 1, 0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0,
+		0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2,
+		2, 2, 0, 0,
+		0, 4, 10, 0, 0, 0, 33, 3,
+		2, 4, 10, 1, 2, 3, 0, 0,
+		1, 4, 10, 0, 0, 0, 33, 0,
+		1, 2, 0, 0,
+	})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		trieMap := triemap.New[byte]()
+		linearMap := triemap.NewLinearMap[byte]()
+
+		// Each operation is encoded as an opcode, a family (2 for the zero
+		// address, otherwise even for IPv4 and odd for IPv6), an address, a
+		// prefix length (which may be one too long) and a value.
+		for len(data) >= 2 {
+			op, family := data[0]%3, data[1]
+			data = data[2:]
+
+			n := 4
+			if family == 2 {
+				n = 0
+			} else if family%2 == 1 {
+				n = 16
+			}
+			if len(data) < n+2 {
+				return
+			}
+			addr, _ := netip.AddrFromSlice(data[:n])
+			bits, value := int(data[n])%(n*8+2), data[n+1]
+			data = data[n+2:]
+
+			prefix := netip.PrefixFrom(addr, bits)
+
+			switch op {
+			case 0:
+				trieMap.Insert(prefix, value)
+				linearMap.Insert(prefix, value)
+			case 1:
+				require.Equal(t, linearMap.Remove(prefix), trieMap.Remove(prefix), "remove %s", prefix)
+			case 2:
+				expectedValue, expectedOK := linearMap.Get(addr)
+				value, ok := trieMap.Get(addr)
+				require.Equal(t, expectedOK, ok, "get %s", addr)
+				require.Equal(t, expectedValue, value, "get %s", addr)
+			}
+
+			require.NoError(t, trieMap.Validate())
+		}
+	})
+}
@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap
+
+import (
+	"net/netip"
+	"slices"
+)
+
+// Lookup is the result of looking up an address in a TrieMap.
+type Lookup[V comparable] struct {
+	// Entry is the longest matching prefix and its value.
+	Entry[V]
+	// Found is true if any prefix matched.
+	Found bool
+	// DefaultRoute is true if a default route (a prefix of length 0) was
+	// among the matching prefixes.
+	DefaultRoute bool
+
+	chain []Entry[V]
+}
+
+// Lookup returns the longest prefix matching addr and its value, along with
+// whether a default route also matched. The full chain of matches is not
+// collected, use LookupWithChain if it is needed.
+func (t *TrieMap[V]) Lookup(addr netip.Addr) Lookup[V] {
+	return t.lookup(addr, false)
+}
+
+// LookupWithChain is like Lookup but also collects the full chain of matches
+// in the same walk of the trie, see Lookup.Chain.
+func (t *TrieMap[V]) LookupWithChain(addr netip.Addr) Lookup[V] {
+	return t.lookup(addr, true)
+}
+
+func (t *TrieMap[V]) lookup(addr netip.Addr, withChain bool) Lookup[V] {
+	mu := t.mu.RLockAddr(addr)
+	defer mu.RUnlock()
+
+	var l Lookup[V]
+	t.trieMap.matches(addr, func(value *nodeValue) bool {
+		l.Entry = Entry[V]{Prefix: value.prefix, Value: t.primary(value)}
+		l.Found = true
+		if value.prefix.Bits() == 0 {
+			l.DefaultRoute = true
+		}
+		if withChain {
+			l.chain = append(l.chain, l.Entry)
+		}
+		return true
+	})
+	return l
+}
+
+// Chain returns every prefix matching the looked up address and its value,
+// ordered from the least to the most specific. The last entry is always the
+// lookup's Entry. It is nil unless the lookup was made with LookupWithChain.
+func (l Lookup[V]) Chain() []Entry[V] {
+	return l.chain
+}
+
+// GetAll returns the values of every prefix containing addr, ordered from the
+// most specific (longest) prefix to the least specific. It returns nil if no
+// prefix matches.
+func (t *TrieMap[V]) GetAll(addr netip.Addr) []V {
+	entries := t.GetAllEntries(addr)
+	if entries == nil {
+		return nil
+	}
+
+	values := make([]V, len(entries))
+	for i, entry := range entries {
+		values[i] = entry.Value
+	}
+	return values
+}
+
+// GetAllEntries is like GetAll but also returns the matching prefixes.
+func (t *TrieMap[V]) GetAllEntries(addr netip.Addr) []Entry[V] {
+	mu := t.mu.RLockAddr(addr)
+	defer mu.RUnlock()
+
+	var entries []Entry[V]
+	t.trieMap.matches(addr, func(value *nodeValue) bool {
+		entries = append(entries, Entry[V]{Prefix: value.prefix, Value: t.primary(value)})
+		return true
+	})
+	slices.Reverse(entries)
+	return entries
+}
@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/triemap"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrieMapLookup(t *testing.T) {
+	trieMap := triemap.New[string]()
+	trieMap.Insert(netip.MustParsePrefix("0.0.0.0/0"), "default")
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	trieMap.Insert(netip.MustParsePrefix("10.1.0.0/16"), "b")
+	trieMap.Insert(netip.MustParsePrefix("10.1.1.0/24"), "c")
+	trieMap.Insert(netip.MustParsePrefix("10.1.1.1/32"), "d")
+	trieMap.Insert(netip.MustParsePrefix("fd00::/8"), "e")
+
+	l := trieMap.Lookup(netip.MustParseAddr("10.1.2.3"))
+	require.True(t, l.Found)
+	require.True(t, l.DefaultRoute)
+	require.Equal(t, netip.MustParsePrefix("10.1.0.0/16"), l.Prefix)
+	require.Equal(t, "b", l.Value)
+	// The chain is only collected on request.
+	require.Nil(t, l.Chain())
+
+	l = trieMap.LookupWithChain(netip.MustParseAddr("10.1.2.3"))
+	require.True(t, l.Found)
+	require.True(t, l.DefaultRoute)
+	require.Equal(t, "b", l.Value)
+
+	require.Equal(t, []triemap.Entry[string]{
+		{Prefix: netip.MustParsePrefix("0.0.0.0/0"), Value: "default"},
+		{Prefix: netip.MustParsePrefix("10.0.0.0/8"), Value: "a"},
+		{Prefix: netip.MustParsePrefix("10.1.0.0/16"), Value: "b"},
+	}, l.Chain())
+
+	// Host routes are matched.
+	l = trieMap.LookupWithChain(netip.MustParseAddr("10.1.1.1"))
+	require.Equal(t, "d", l.Value)
+	require.Len(t, l.Chain(), 5)
+
+	// The chain reflects the map at the time of the lookup, consistent with
+	// the best match.
+	require.True(t, trieMap.Remove(netip.MustParsePrefix("10.1.1.1/32")))
+	require.Equal(t, l.Entry, l.Chain()[len(l.Chain())-1])
+	require.Len(t, l.Chain(), 5)
+
+	l = trieMap.Lookup(netip.MustParseAddr("fd00::1"))
+	require.True(t, l.Found)
+	require.False(t, l.DefaultRoute)
+	require.Equal(t, "e", l.Value)
+
+	l = trieMap.LookupWithChain(netip.MustParseAddr("2001:db8::1"))
+	require.False(t, l.Found)
+	require.Empty(t, l.Chain())
+
+	require.Empty(t, triemap.Lookup[string]{}.Chain())
+}
+
+func TestTrieMapGetAll(t *testing.T) {
+	trieMap := triemap.New[string]()
+	trieMap.Insert(netip.MustParsePrefix("0.0.0.0/0"), "default")
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	trieMap.Insert(netip.MustParsePrefix("10.1.0.0/16"), "b")
+	trieMap.Insert(netip.MustParsePrefix("10.2.0.0/16"), "c")
+	trieMap.Insert(netip.MustParsePrefix("10.1.1.0/24"), "d")
+
+	// Ordered from the most to the least specific.
+	require.Equal(t, []string{"d", "b", "a", "default"}, trieMap.GetAll(netip.MustParseAddr("10.1.1.1")))
+	require.Equal(t, []string{"a", "default"}, trieMap.GetAll(netip.MustParseAddr("10.3.0.1")))
+
+	require.Equal(t, []triemap.Entry[string]{
+		{Prefix: netip.MustParsePrefix("10.2.0.0/16"), Value: "c"},
+		{Prefix: netip.MustParsePrefix("10.0.0.0/8"), Value: "a"},
+		{Prefix: netip.MustParsePrefix("0.0.0.0/0"), Value: "default"},
+	}, trieMap.GetAllEntries(netip.MustParseAddr("10.2.0.1")))
+
+	require.Empty(t, trieMap.GetAll(netip.MustParseAddr("fd00::1")))
+	require.Empty(t, trieMap.GetAllEntries(netip.MustParseAddr("fd00::1")))
+}
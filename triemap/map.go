@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap
+
+import (
+	"maps"
+	"net/netip"
+	"slices"
+)
+
+// FromMap returns a new TrieMap populated with the entries of m, as if by
+// Insert. Keys that only differ in their host bits (eg. 10.0.0.0/8 and
+// 10.1.2.3/8) refer to the same prefix. As keys are inserted in ascending
+// order, the value of the key with the greatest address wins, or comes last in
+// multimap mode (see WithMultimap).
+func FromMap[V comparable](m map[netip.Prefix]V, opts ...Option) *TrieMap[V] {
+	t := New[V](opts...)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Map iteration order is random, so sort the keys to make the result
+	// deterministic.
+	prefixes := slices.SortedFunc(maps.Keys(m), func(a, b netip.Prefix) int {
+		if c := comparePrefixes(a, b); c != 0 {
+			return c
+		}
+		return a.Addr().Compare(b.Addr())
+	})
+	for _, prefix := range prefixes {
+		if !prefix.IsValid() {
+			continue
+		}
+		key := t.keyFor(m[prefix])
+		if t.multimap {
+			t.trieMap.insertWeighted(prefix, key, 1)
+		} else {
+			t.insert(prefix, key)
+		}
+	}
+
+	return t
+}
+
+// ToMap returns the entries of the TrieMap as a map of exact prefixes to their
+// (primary) values. The map has no ordering and retains none of the trie's
+// longest prefix matching behavior.
+func (t *TrieMap[V]) ToMap() map[netip.Prefix]V {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	m := make(map[netip.Prefix]V)
+	t.trieMap.walk(func(value *nodeValue) bool {
+		m[value.prefix] = t.primary(value)
+		return true
+	})
+	return m
+}
@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/triemap"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrieMapFromMap(t *testing.T) {
+	m := make(map[netip.Prefix]string)
+	for value, prefixes := range testPrefixes {
+		for _, prefix := range prefixes {
+			m[prefix] = value
+		}
+	}
+
+	trieMap := triemap.FromMap(m)
+	for _, tc := range testCases {
+		value, contains := trieMap.Get(tc.Addr)
+		require.Equal(t, tc.ExpectedValue != "", contains)
+		require.Equal(t, tc.ExpectedValue, value)
+	}
+
+	require.Equal(t, m, trieMap.ToMap())
+
+	require.Empty(t, triemap.New[string]().ToMap())
+}
+
+func TestTrieMapFromMapHostBits(t *testing.T) {
+	m := map[netip.Prefix]string{
+		netip.MustParsePrefix("10.0.0.0/8"): "a",
+		netip.MustParsePrefix("10.1.2.3/8"): "b",
+		netip.MustParsePrefix("10.0.0.1/8"): "c",
+	}
+
+	// The key with the greatest address always wins, regardless of map
+	// iteration order.
+	for range 10 {
+		trieMap := triemap.FromMap(m)
+		require.Equal(t, 1, trieMap.Len())
+
+		value, ok := trieMap.Get(netip.MustParseAddr("10.200.0.1"))
+		require.True(t, ok)
+		require.Equal(t, "b", value)
+		require.NoError(t, trieMap.Validate())
+	}
+
+	// In multimap mode every value is kept.
+	trieMap := triemap.FromMap(m, triemap.WithMultimap())
+	require.Equal(t, []string{"a", "c", "b"}, trieMap.GetValues(netip.MustParseAddr("10.200.0.1")))
+	require.NoError(t, trieMap.Validate())
+}
@@ -0,0 +1,40 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap
+
+import "net/netip"
+
+// Merge inserts every entry of other into the TrieMap. If a prefix is already
+// present, onConflict is called with the prefix, the existing value and the
+// incoming value, and the prefix is set to the value it returns. If
+// onConflict is nil, the incoming value wins. Only the primary value of each
+// of other's prefixes is merged (see InsertWeighted).
+//
+// The conflict callback is called with the write lock held, so it must not
+// call back into the TrieMap. Other is snapshotted under its own read lock
+// before the write lock is taken, so the two locks are never held together
+// (and a TrieMap can be merged into itself), but the merge is not atomic with
+// respect to concurrent mutations of other.
+func (t *TrieMap[V]) Merge(other *TrieMap[V], onConflict func(prefix netip.Prefix, existing, incoming V) V) {
+	entries := other.entries()
+
+	t.mu.Lock()
+	for i, e := range entries {
+		if existing := t.trieMap.find(e.Prefix); existing != nil && onConflict != nil {
+			entries[i].Value = onConflict(e.Prefix, t.primary(existing), e.Value)
+		}
+		t.insert(entries[i].Prefix, t.keyFor(entries[i].Value))
+	}
+	t.mu.Unlock()
+
+	for _, e := range entries {
+		t.notifyInsert(e.Prefix, e.Value)
+	}
+}
@@ -0,0 +1,96 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/triemap"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrieMapMerge(t *testing.T) {
+	trieMap := triemap.New[string](triemap.WithValueIndex())
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	trieMap.Insert(netip.MustParsePrefix("192.168.0.0/16"), "b")
+
+	other := triemap.New[string]()
+	other.Insert(netip.MustParsePrefix("10.0.0.0/8"), "c")
+	other.Insert(netip.MustParsePrefix("10.1.0.0/16"), "b")
+	other.Insert(netip.MustParsePrefix("fd00::/8"), "d")
+
+	type conflict struct {
+		prefix             netip.Prefix
+		existing, incoming string
+	}
+	var conflicts []conflict
+	trieMap.Merge(other, func(prefix netip.Prefix, existing, incoming string) string {
+		conflicts = append(conflicts, conflict{prefix, existing, incoming})
+		return existing + incoming
+	})
+
+	// Only exact prefix matches conflict.
+	require.Equal(t, []conflict{
+		{netip.MustParsePrefix("10.0.0.0/8"), "a", "c"},
+	}, conflicts)
+
+	require.Equal(t, map[netip.Prefix]string{
+		netip.MustParsePrefix("10.0.0.0/8"):     "ac",
+		netip.MustParsePrefix("10.1.0.0/16"):    "b",
+		netip.MustParsePrefix("192.168.0.0/16"): "b",
+		netip.MustParsePrefix("fd00::/8"):       "d",
+	}, trieMap.ToMap())
+
+	// The replaced value is dropped, and repeated values share a key.
+	require.Empty(t, trieMap.PrefixesFor("a"))
+	require.Len(t, trieMap.PrefixesFor("b"), 2)
+	require.NoError(t, trieMap.Validate())
+
+	// The other map is unchanged.
+	require.Equal(t, 3, other.Len())
+	require.NoError(t, other.Validate())
+}
+
+func TestTrieMapMergeIncomingWins(t *testing.T) {
+	trieMap := triemap.New[string]()
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	trieMap.Insert(netip.MustParsePrefix("10.1.0.0/16"), "b")
+
+	// Overlapping, but not identical, prefixes don't conflict.
+	overlapping := triemap.New[string]()
+	overlapping.Insert(netip.MustParsePrefix("10.0.0.0/8"), "c")
+	overlapping.Insert(netip.MustParsePrefix("10.1.2.0/24"), "a")
+	trieMap.Merge(overlapping, nil)
+
+	require.Equal(t, map[netip.Prefix]string{
+		netip.MustParsePrefix("10.0.0.0/8"):  "c",
+		netip.MustParsePrefix("10.1.0.0/16"): "b",
+		netip.MustParsePrefix("10.1.2.0/24"): "a",
+	}, trieMap.ToMap())
+	require.Equal(t, 3, trieMap.ValueLen())
+	require.NoError(t, trieMap.Validate())
+
+	// Disjoint prefixes are simply added.
+	disjoint := triemap.New[string]()
+	disjoint.Insert(netip.MustParsePrefix("192.168.0.0/16"), "d")
+	disjoint.Insert(netip.MustParsePrefix("fd00::/8"), "a")
+	trieMap.Merge(disjoint, nil)
+
+	require.Equal(t, 5, trieMap.Len())
+	require.Equal(t, 4, trieMap.ValueLen())
+	require.NoError(t, trieMap.Validate())
+
+	// Merging a map into itself changes nothing.
+	before := trieMap.Clone()
+	trieMap.Merge(trieMap, nil)
+	require.True(t, before.Equal(trieMap))
+	require.NoError(t, trieMap.Validate())
+}
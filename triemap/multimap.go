@@ -0,0 +1,32 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap
+
+import "net/netip"
+
+// GetValues returns every value associated with the longest prefix matching
+// addr, in the order they were inserted. It is primarily intended for use in
+// multimap mode (see WithMultimap), otherwise a prefix holds a single value
+// unless weighted values have been inserted.
+func (t *TrieMap[V]) GetValues(addr netip.Addr) []V {
+	mu := t.mu.RLockAddr(addr)
+	defer mu.RUnlock()
+
+	v := t.trieMap.get(addr)
+	if v == nil {
+		return nil
+	}
+
+	values := make([]V, len(v.keys))
+	for i, k := range v.keys {
+		values[i] = t.keyToValue[k.key]
+	}
+	return values
+}
@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/triemap"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrieMapMultimap(t *testing.T) {
+	trieMap := triemap.New[string](triemap.WithMultimap())
+
+	prefix := netip.MustParsePrefix("10.0.0.0/8")
+	trieMap.Insert(prefix, "prod")
+	trieMap.Insert(prefix, "eu-west")
+	trieMap.Insert(prefix, "prod")
+	trieMap.Insert(netip.MustParsePrefix("10.1.0.0/16"), "staging")
+
+	addr := netip.MustParseAddr("10.2.3.4")
+	require.Equal(t, []string{"prod", "eu-west"}, trieMap.GetValues(addr))
+
+	// Get returns the first value inserted.
+	value, ok := trieMap.Get(addr)
+	require.True(t, ok)
+	require.Equal(t, "prod", value)
+
+	// The longest match wins.
+	require.Equal(t, []string{"staging"}, trieMap.GetValues(netip.MustParseAddr("10.1.2.3")))
+
+	require.Nil(t, trieMap.GetValues(netip.MustParseAddr("192.168.1.1")))
+
+	// Removing a value removes it from the set.
+	trieMap.RemoveValue("prod")
+	require.Equal(t, []string{"eu-west"}, trieMap.GetValues(addr))
+
+	// Removing the prefix removes the whole set.
+	require.True(t, trieMap.Remove(prefix))
+	require.Nil(t, trieMap.GetValues(addr))
+}
+
+func TestTrieMapGetValuesSingle(t *testing.T) {
+	trieMap := triemap.New[string]()
+
+	prefix := netip.MustParsePrefix("10.0.0.0/8")
+	trieMap.Insert(prefix, "a")
+	trieMap.Insert(prefix, "b")
+
+	require.Equal(t, []string{"b"}, trieMap.GetValues(netip.MustParseAddr("10.0.0.1")))
+}
+
+func TestTrieMapWithLess(t *testing.T) {
+	type route struct {
+		nextHop string
+		metric  int
+	}
+
+	trieMap := triemap.New[route](triemap.WithMultimap(), triemap.WithLess(func(a, b route) bool {
+		return a.metric < b.metric
+	}))
+
+	prefix := netip.MustParsePrefix("10.0.0.0/8")
+	trieMap.Insert(prefix, route{nextHop: "a", metric: 20})
+	trieMap.Insert(prefix, route{nextHop: "b", metric: 10})
+	trieMap.Insert(prefix, route{nextHop: "c", metric: 10})
+	trieMap.Insert(prefix, route{nextHop: "d", metric: 30})
+
+	// The lowest metric wins, ties are broken by insertion order.
+	value, ok := trieMap.Get(netip.MustParseAddr("10.0.0.1"))
+	require.True(t, ok)
+	require.Equal(t, "b", value.nextHop)
+
+	require.Equal(t, map[netip.Prefix]route{prefix: {nextHop: "b", metric: 10}}, trieMap.ToMap())
+
+	require.Panics(t, func() {
+		triemap.New[string](triemap.WithLess(func(a, b int) bool { return a < b }))
+	})
+}
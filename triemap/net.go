@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+)
+
+// InsertNet is like Insert but takes a net.IPNet, for interoperability with
+// code using the net package types. IPv4 networks are inserted as IPv4
+// prefixes, whether they are represented with a 4 or 16 byte IP. It returns an
+// error if ipnet is nil or does not have a valid, canonical mask.
+func (t *TrieMap[V]) InsertNet(ipnet *net.IPNet, value V) error {
+	prefix, err := prefixFromIPNet(ipnet)
+	if err != nil {
+		return err
+	}
+
+	t.Insert(prefix, value)
+	return nil
+}
+
+// GetIP is like Get but takes a net.IP, for interoperability with code using
+// the net package types. IPv4-mapped IPv6 addresses (including the 16 byte
+// representation of IPv4 addresses) are matched against IPv4 prefixes.
+func (t *TrieMap[V]) GetIP(ip net.IP) (value V, contains bool) {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return
+	}
+	return t.Get(addr.Unmap())
+}
+
+// prefixFromIPNet converts a net.IPNet to a netip.Prefix.
+func prefixFromIPNet(ipnet *net.IPNet) (netip.Prefix, error) {
+	if ipnet == nil {
+		return netip.Prefix{}, fmt.Errorf("nil network")
+	}
+
+	addr, ok := netip.AddrFromSlice(ipnet.IP)
+	if !ok {
+		return netip.Prefix{}, fmt.Errorf("invalid network address: %s", ipnet.IP)
+	}
+
+	ones, bits := ipnet.Mask.Size()
+	if bits == 0 {
+		return netip.Prefix{}, fmt.Errorf("invalid network mask: %s", ipnet.Mask)
+	}
+
+	if addr.Is4In6() {
+		addr = addr.Unmap()
+		// A 16 byte mask for an IPv4-mapped address also covers the mapping
+		// prefix.
+		if bits == 8*net.IPv6len {
+			if ones < 96 {
+				return netip.Prefix{}, fmt.Errorf("invalid network mask for IPv4 address: %s", ipnet.Mask)
+			}
+			ones, bits = ones-96, bits-96
+		}
+	}
+	if addr.BitLen() != bits {
+		return netip.Prefix{}, fmt.Errorf("network mask does not match address family: %s", ipnet)
+	}
+
+	return netip.PrefixFrom(addr, ones), nil
+}
@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap_test
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/triemap"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrieMapInsertNet(t *testing.T) {
+	trieMap := triemap.New[string]()
+
+	_, v4, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+	require.NoError(t, trieMap.InsertNet(v4, "a"))
+
+	_, v6, err := net.ParseCIDR("2001:db8::/32")
+	require.NoError(t, err)
+	require.NoError(t, trieMap.InsertNet(v6, "b"))
+
+	// An IPv4 network with a 16 byte IP and a 4 byte mask.
+	require.NoError(t, trieMap.InsertNet(&net.IPNet{
+		IP:   net.ParseIP("192.168.1.0").To16(),
+		Mask: net.CIDRMask(24, 32),
+	}, "c"))
+
+	// An IPv4 network with a 16 byte IP and a 16 byte mask.
+	require.NoError(t, trieMap.InsertNet(&net.IPNet{
+		IP:   net.ParseIP("172.16.0.0").To16(),
+		Mask: net.CIDRMask(96+12, 128),
+	}, "d"))
+
+	require.Equal(t, 4, trieMap.Len())
+
+	// Everything was stored as an IPv4 or IPv6 prefix as appropriate.
+	for prefix, expected := range map[string]string{
+		"10.0.0.0/8":     "a",
+		"2001:db8::/32":  "b",
+		"192.168.1.0/24": "c",
+		"172.16.0.0/12":  "d",
+	} {
+		value, ok := trieMap.LookupPrefix(netip.MustParsePrefix(prefix))
+		require.True(t, ok, prefix)
+		require.Equal(t, expected, value, prefix)
+	}
+
+	tests := map[string]string{
+		"10.1.2.3":         "a",
+		"::ffff:10.1.2.3":  "a",
+		"2001:db8::1":      "b",
+		"192.168.1.1":      "c",
+		"172.31.255.255":   "d",
+		"192.168.2.1":      "",
+		"fd00::1":          "",
+		"::ffff:192.0.2.1": "",
+	}
+	for ip, expected := range tests {
+		value, ok := trieMap.GetIP(net.ParseIP(ip))
+		require.Equal(t, expected != "", ok, ip)
+		require.Equal(t, expected, value, ip)
+
+		// The 4 byte representation of IPv4 addresses also matches.
+		if ip4 := net.ParseIP(ip).To4(); ip4 != nil {
+			value, ok = trieMap.GetIP(ip4)
+			require.Equal(t, expected != "", ok, ip)
+			require.Equal(t, expected, value, ip)
+		}
+	}
+
+	_, ok := trieMap.GetIP(nil)
+	require.False(t, ok)
+
+	require.Error(t, trieMap.InsertNet(nil, "e"))
+	require.Error(t, trieMap.InsertNet(&net.IPNet{
+		IP:   net.ParseIP("10.0.0.0").To4(),
+		Mask: net.IPMask{0xff, 0x00, 0xff, 0x00},
+	}, "e"))
+	require.Error(t, trieMap.InsertNet(&net.IPNet{
+		IP:   net.ParseIP("2001:db8::"),
+		Mask: net.CIDRMask(24, 32),
+	}, "e"))
+	require.Equal(t, 4, trieMap.Len())
+}
@@ -0,0 +1,95 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap
+
+import (
+	"net/netip"
+
+	"github.com/noisysockets/util/internal/addrbits"
+)
+
+// WalkFrom returns up to limit entries, in ascending address order (IPv4
+// before IPv6, shorter prefixes first), that are ordered after the given
+// prefix. The after prefix does not need to be present in the TrieMap, and
+// the zero prefix starts from the beginning.
+//
+// The returned cursor is the prefix to pass as after to fetch the next page,
+// or the zero prefix if there are no more entries.
+func (t *TrieMap[V]) WalkFrom(after netip.Prefix, limit int) (entries []Entry[V], cursor netip.Prefix) {
+	if limit <= 0 {
+		return nil, netip.Prefix{}
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	// Fetch one more entry than needed to find out if there is another page.
+	t.trieMap.walkAfter(after, func(value *nodeValue) bool {
+		entries = append(entries, Entry[V]{Prefix: value.prefix, Value: t.primary(value)})
+		return len(entries) <= limit
+	})
+
+	if len(entries) > limit {
+		entries = entries[:limit]
+		cursor = entries[limit-1].Prefix
+	}
+	return entries, cursor
+}
+
+// walkAfter is like walk but only visits values ordered after the given
+// prefix. Subtrees that are entirely ordered before the prefix are skipped.
+func (t *trieMap) walkAfter(after netip.Prefix, fn func(value *nodeValue) bool) {
+	if !after.IsValid() {
+		t.walk(fn)
+		return
+	}
+
+	afterAddr, afterTotalBits := addrbits.FromAddr(after.Masked().Addr())
+
+	type frame struct {
+		node      *trieNode
+		totalBits int
+	}
+
+	var stack []frame
+	if t.ipv6Root != nil {
+		stack = append(stack, frame{node: t.ipv6Root, totalBits: 128})
+	}
+	// IPv4 is ordered before IPv6.
+	if t.ipv4Root != nil && afterTotalBits == 32 {
+		stack = append(stack, frame{node: t.ipv4Root, totalBits: 32})
+	}
+
+	for len(stack) > 0 {
+		curr := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if curr.totalBits == afterTotalBits {
+			last := curr.node.addr.Or(addrbits.HostMask(curr.totalBits - curr.node.bits))
+			if last.Cmp(afterAddr) < 0 {
+				continue
+			}
+		}
+
+		if curr.node.value != nil && comparePrefixes(curr.node.value.prefix, after) > 0 {
+			if !fn(curr.node.value) {
+				return
+			}
+		}
+
+		// Push child1 first so that child0 is visited first.
+		if curr.node.child1 != nil {
+			stack = append(stack, frame{node: curr.node.child1, totalBits: curr.totalBits})
+		}
+		if curr.node.child0 != nil {
+			stack = append(stack, frame{node: curr.node.child0, totalBits: curr.totalBits})
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/triemap"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrieMapWalkFrom(t *testing.T) {
+	trieMap := triemap.New[string]()
+	for value, prefixes := range testPrefixes {
+		for _, prefix := range prefixes {
+			trieMap.Insert(prefix, value)
+		}
+	}
+
+	expected := []netip.Prefix{
+		netip.MustParsePrefix("35.180.0.0/16"),
+		netip.MustParsePrefix("52.93.127.17/32"),
+		netip.MustParsePrefix("52.93.127.172/31"),
+		netip.MustParsePrefix("52.93.127.173/32"),
+		netip.MustParsePrefix("52.93.127.174/32"),
+		netip.MustParsePrefix("52.93.127.175/32"),
+		netip.MustParsePrefix("52.93.127.176/32"),
+		netip.MustParsePrefix("52.93.127.177/32"),
+		netip.MustParsePrefix("52.93.127.178/32"),
+		netip.MustParsePrefix("52.93.127.179/32"),
+		netip.MustParsePrefix("52.94.76.0/22"),
+		netip.MustParsePrefix("2400:6500:0:9::2/128"),
+		netip.MustParsePrefix("2600:1f01:4874::/47"),
+	}
+
+	t.Run("Pages", func(t *testing.T) {
+		var prefixes []netip.Prefix
+		var pages int
+		var cursor netip.Prefix
+		for {
+			entries, next := trieMap.WalkFrom(cursor, 4)
+			pages++
+			for _, entry := range entries {
+				prefixes = append(prefixes, entry.Prefix)
+			}
+			if !next.IsValid() {
+				break
+			}
+			cursor = next
+		}
+
+		require.Equal(t, 4, pages)
+		require.Equal(t, expected, prefixes)
+	})
+
+	t.Run("Exact Page", func(t *testing.T) {
+		entries, cursor := trieMap.WalkFrom(netip.Prefix{}, len(expected))
+		require.Len(t, entries, len(expected))
+		require.False(t, cursor.IsValid())
+	})
+
+	t.Run("Values", func(t *testing.T) {
+		entries, _ := trieMap.WalkFrom(netip.MustParsePrefix("52.93.127.172/31"), 1)
+		require.Equal(t, []triemap.Entry[string]{
+			{Prefix: netip.MustParsePrefix("52.93.127.173/32"), Value: "us-east-1"},
+		}, entries)
+	})
+
+	t.Run("Absent Cursor", func(t *testing.T) {
+		// 52.93.127.172/30 is not present, but is ordered between the
+		// 52.93.127.17/32 and the 52.93.127.172/31.
+		entries, cursor := trieMap.WalkFrom(netip.MustParsePrefix("52.93.127.172/30"), 2)
+		require.Equal(t, netip.MustParsePrefix("52.93.127.172/31"), entries[0].Prefix)
+		require.Equal(t, netip.MustParsePrefix("52.93.127.173/32"), cursor)
+
+		entries, cursor = trieMap.WalkFrom(netip.MustParsePrefix("100.0.0.0/8"), 10)
+		require.Len(t, entries, 2)
+		require.Equal(t, netip.MustParsePrefix("2400:6500:0:9::2/128"), entries[0].Prefix)
+		require.False(t, cursor.IsValid())
+
+		entries, _ = trieMap.WalkFrom(netip.MustParsePrefix("2500::/16"), 10)
+		require.Len(t, entries, 1)
+		require.Equal(t, netip.MustParsePrefix("2600:1f01:4874::/47"), entries[0].Prefix)
+
+		entries, cursor = trieMap.WalkFrom(netip.MustParsePrefix("ffff::/16"), 10)
+		require.Empty(t, entries)
+		require.False(t, cursor.IsValid())
+	})
+}
@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"sync"
+	"unsafe"
+)
+
+const (
+	// lockShardBits is the log2 of the number of reader shards.
+	lockShardBits = 4
+	numLockShards = 1 << lockShardBits
+	// cacheLineSize is the assumed size of a CPU cache line.
+	cacheLineSize = 64
+)
+
+// shardedRWMutex is a reader/writer mutual exclusion lock that is split into
+// multiple shards, each on its own cache line. Readers only lock a single
+// shard, so that readers on different shards don't contend on the same cache
+// line, whereas writers must lock every shard.
+//
+// This trades more expensive writes for cheaper concurrent reads, which suits
+// the read-mostly workloads TrieMap is designed for.
+type shardedRWMutex struct {
+	shards [numLockShards]paddedRWMutex
+}
+
+type paddedRWMutex struct {
+	sync.RWMutex
+	_ [cacheLineSize - unsafe.Sizeof(sync.RWMutex{})%cacheLineSize]byte
+}
+
+// Lock locks every shard for writing.
+func (m *shardedRWMutex) Lock() {
+	for i := range m.shards {
+		m.shards[i].Lock()
+	}
+}
+
+// Unlock unlocks every shard for writing.
+func (m *shardedRWMutex) Unlock() {
+	for i := len(m.shards) - 1; i >= 0; i-- {
+		m.shards[i].Unlock()
+	}
+}
+
+// RLock locks the first shard for reading, it's intended for infrequent
+// readers that aren't associated with a particular address.
+func (m *shardedRWMutex) RLock() {
+	m.shards[0].RLock()
+}
+
+// RUnlock undoes a single RLock call.
+func (m *shardedRWMutex) RUnlock() {
+	m.shards[0].RUnlock()
+}
+
+// RLockAddr locks the shard associated with addr for reading and returns it
+// so that the caller can unlock it.
+func (m *shardedRWMutex) RLockAddr(addr netip.Addr) *sync.RWMutex {
+	b := addr.As16()
+	h := binary.LittleEndian.Uint64(b[:8]) ^ binary.LittleEndian.Uint64(b[8:])
+	// Fibonacci hashing, the top bits are the best distributed.
+	h *= 0x9e3779b97f4a7c15
+
+	mu := &m.shards[h>>(64-lockShardBits)].RWMutex
+	mu.RLock()
+	return mu
+}
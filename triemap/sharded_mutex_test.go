@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap
+
+import (
+	"encoding/binary"
+	"math/rand/v2"
+	"net/netip"
+	"sync"
+	"testing"
+)
+
+// BenchmarkTrieMapLocking compares the sharded reader/writer lock used by
+// TrieMap against a plain sync.RWMutex, for parallel lookups of a wide set of
+// addresses (so that readers are spread across every shard) and for the
+// uncontended write lock.
+func BenchmarkTrieMapLocking(b *testing.B) {
+	t := New[int]()
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := 0; i < 10000; i++ {
+		var ip4 [4]byte
+		binary.BigEndian.PutUint32(ip4[:], rng.Uint32())
+		t.Insert(netip.PrefixFrom(netip.AddrFrom4(ip4), 8+rng.IntN(25)).Masked(), i%32)
+	}
+
+	addrs := make([]netip.Addr, 4096)
+	for i := range addrs {
+		var ip4 [4]byte
+		binary.BigEndian.PutUint32(ip4[:], rng.Uint32())
+		addrs[i] = netip.AddrFrom4(ip4)
+	}
+
+	var mu sync.RWMutex
+
+	b.Run("Get/Sharded", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			i := rand.IntN(len(addrs))
+			for pb.Next() {
+				addr := addrs[i%len(addrs)]
+				shard := t.mu.RLockAddr(addr)
+				_ = t.trieMap.get(addr)
+				shard.RUnlock()
+				i++
+			}
+		})
+	})
+
+	b.Run("Get/RWMutex", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			i := rand.IntN(len(addrs))
+			for pb.Next() {
+				addr := addrs[i%len(addrs)]
+				mu.RLock()
+				_ = t.trieMap.get(addr)
+				mu.RUnlock()
+				i++
+			}
+		})
+	})
+
+	b.Run("Lock/Sharded", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			t.mu.Lock()
+			t.mu.Unlock()
+		}
+	})
+
+	b.Run("Lock/RWMutex", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			mu.Lock()
+			mu.Unlock()
+		}
+	})
+}
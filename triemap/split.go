@@ -0,0 +1,47 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap
+
+// Split returns two new TrieMaps, one holding the IPv4 prefixes and the other
+// the IPv6 prefixes of the TrieMap, both configured with the same options.
+// The returned maps are fully independent of the original and of each other.
+func (t *TrieMap[V]) Split() (v4, v6 *TrieMap[V]) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	v4, v6 = newWithOptions[V](t.options), newWithOptions[V](t.options)
+	t.trieMap.walkNodes(func(value *nodeValue) bool {
+		t.copyValue(v4, value)
+		return true
+	}, t.trieMap.ipv4Root)
+	t.trieMap.walkNodes(func(value *nodeValue) bool {
+		t.copyValue(v6, value)
+		return true
+	}, t.trieMap.ipv6Root)
+	t.copyDisabled(v4)
+	t.copyDisabled(v6)
+	return v4, v6
+}
+
+// copyDisabled disables in dst every value that is disabled in t, and has
+// been copied into dst.
+func (t *TrieMap[V]) copyDisabled(dst *TrieMap[V]) {
+	for key := range t.trieMap.disabled {
+		// Values that weren't copied are skipped, rather than being added to
+		// the bimap of dst without any prefixes referencing them.
+		if _, ok := dst.valueToKey[t.keyToValue[key]]; !ok {
+			continue
+		}
+		if dst.trieMap.disabled == nil {
+			dst.trieMap.disabled = make(map[int]struct{})
+		}
+		dst.trieMap.disabled[dst.keyFor(t.keyToValue[key])] = struct{}{}
+	}
+}
@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/triemap"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrieMapSplit(t *testing.T) {
+	trieMap := triemap.New[string](triemap.WithValueIndex())
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	trieMap.Insert(netip.MustParsePrefix("192.168.0.0/16"), "b")
+	trieMap.Insert(netip.MustParsePrefix("fd00::/8"), "a")
+	trieMap.InsertWeighted(netip.MustParsePrefix("2001:db8::/32"), "c", 1)
+	trieMap.InsertWeighted(netip.MustParsePrefix("2001:db8::/32"), "d", 2)
+
+	v4, v6 := trieMap.Split()
+
+	require.Equal(t, map[netip.Prefix]string{
+		netip.MustParsePrefix("10.0.0.0/8"):     "a",
+		netip.MustParsePrefix("192.168.0.0/16"): "b",
+	}, v4.ToMap())
+
+	require.Equal(t, map[netip.Prefix]string{
+		netip.MustParsePrefix("fd00::/8"):      "a",
+		netip.MustParsePrefix("2001:db8::/32"): "c",
+	}, v6.ToMap())
+
+	// Weighted values are preserved.
+	require.Equal(t, []string{"c", "d"}, v6.GetValues(netip.MustParseAddr("2001:db8::1")))
+
+	require.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}, v4.PrefixesFor("a"))
+	require.Empty(t, v4.PrefixesFor("c"))
+
+	// The split maps are independent of the original.
+	trieMap.RemoveValue("a")
+	v4.Insert(netip.MustParsePrefix("172.16.0.0/12"), "e")
+
+	value, ok := v4.Get(netip.MustParseAddr("10.0.0.1"))
+	require.True(t, ok)
+	require.Equal(t, "a", value)
+
+	_, ok = trieMap.Get(netip.MustParseAddr("172.16.0.1"))
+	require.False(t, ok)
+}
+
+func TestTrieMapSplitDisabled(t *testing.T) {
+	trieMap := triemap.New[string]()
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	trieMap.Insert(netip.MustParsePrefix("192.168.0.0/16"), "b")
+	trieMap.Insert(netip.MustParsePrefix("fd00::/8"), "c")
+	trieMap.SetEnabled("a", false)
+	trieMap.SetEnabled("c", false)
+
+	v4, v6 := trieMap.Split()
+	require.NoError(t, v4.Validate())
+	require.NoError(t, v6.Validate())
+
+	// Disabled values stay disabled.
+	_, ok := v4.Get(netip.MustParseAddr("10.0.0.1"))
+	require.False(t, ok)
+	_, ok = v6.Get(netip.MustParseAddr("fd00::1"))
+	require.False(t, ok)
+
+	value, ok := v4.Get(netip.MustParseAddr("192.168.0.1"))
+	require.True(t, ok)
+	require.Equal(t, "b", value)
+
+	// And can be re-enabled.
+	v4.SetEnabled("a", true)
+	value, ok = v4.Get(netip.MustParseAddr("10.0.0.1"))
+	require.True(t, ok)
+	require.Equal(t, "a", value)
+}
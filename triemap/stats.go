@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap
+
+// Stats describes the shape of a TrieMap.
+type Stats struct {
+	// IPv4 describes the IPv4 trie.
+	IPv4 FamilyStats
+	// IPv6 describes the IPv6 trie.
+	IPv6 FamilyStats
+	// Values is the number of distinct values across both families.
+	Values int
+}
+
+// FamilyStats describes the trie of a single address family.
+type FamilyStats struct {
+	// Nodes is the total number of nodes in the trie, including the root and
+	// the nodes at which prefixes diverge.
+	Nodes int
+	// Prefixes is the number of nodes holding a prefix.
+	Prefixes int
+	// Values is the number of distinct values referenced by the prefixes.
+	Values int
+	// MaxDepth is the largest number of nodes between the root and a leaf,
+	// 0 if the trie has no more than a root node.
+	MaxDepth int
+}
+
+// Stats returns statistics about the shape of the TrieMap, eg. for capacity
+// planning or to spot pathological inputs. It walks the whole trie under the
+// read lock, so it is linear in the size of the TrieMap.
+func (t *TrieMap[V]) Stats() Stats {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return Stats{
+		IPv4:   t.trieMap.familyStats(t.trieMap.ipv4Root),
+		IPv6:   t.trieMap.familyStats(t.trieMap.ipv6Root),
+		Values: len(t.keyToValue),
+	}
+}
+
+// familyStats computes the statistics of the trie rooted at root.
+func (t *trieMap) familyStats(root *trieNode) FamilyStats {
+	var stats FamilyStats
+	if root == nil {
+		return stats
+	}
+
+	type frame struct {
+		node  *trieNode
+		depth int
+	}
+
+	keys := make(map[int]struct{})
+	stack := []frame{{node: root}}
+	for len(stack) > 0 {
+		curr := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		stats.Nodes++
+		stats.MaxDepth = max(stats.MaxDepth, curr.depth)
+		if curr.node.value != nil {
+			stats.Prefixes++
+			for _, k := range curr.node.value.keys {
+				keys[k.key] = struct{}{}
+			}
+		}
+
+		for _, child := range []*trieNode{curr.node.child0, curr.node.child1} {
+			if child != nil {
+				stack = append(stack, frame{node: child, depth: curr.depth + 1})
+			}
+		}
+	}
+	stats.Values = len(keys)
+	return stats
+}
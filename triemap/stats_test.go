@@ -0,0 +1,46 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/triemap"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrieMapStats(t *testing.T) {
+	trieMap := triemap.New[string]()
+	require.Equal(t, triemap.Stats{}, trieMap.Stats())
+
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	trieMap.Insert(netip.MustParsePrefix("10.1.0.0/16"), "b")
+	trieMap.Insert(netip.MustParsePrefix("10.2.0.0/16"), "a")
+	trieMap.Insert(netip.MustParsePrefix("fd00::/8"), "c")
+
+	require.Equal(t, triemap.Stats{
+		IPv4: triemap.FamilyStats{
+			// The root, 10.0.0.0/8, the two /16s and the node at which they
+			// diverge (10.0.0.0/14).
+			Nodes:    5,
+			Prefixes: 3,
+			Values:   2,
+			MaxDepth: 3,
+		},
+		IPv6: triemap.FamilyStats{
+			Nodes:    2,
+			Prefixes: 1,
+			Values:   1,
+			MaxDepth: 1,
+		},
+		Values: 3,
+	}, trieMap.Stats())
+}
@@ -26,10 +26,16 @@
 package triemap
 
 import (
-	"encoding/binary"
+	"fmt"
+	"iter"
+	"maps"
 	"net/netip"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/noisysockets/util/internal/addrbits"
 	"github.com/noisysockets/util/uint128"
 )
 
@@ -39,11 +45,12 @@ import (
 //
 // # Use NewTrieMap to instantiate
 //
-// Currently this is a simple TrieMap, in the future it may have compression.
+// The trie is path compressed, nodes are only stored for prefixes and the
+// points at which they diverge, rather than for every bit of every prefix.
 //
 // See: https://vincent.bernat.ch/en/blog/2017-ipv4-route-lookup-linux
 type TrieMap[V comparable] struct {
-	mu sync.RWMutex
+	mu shardedRWMutex
 	// This is the real triemap, but it only maps netip.Prefix / netip.Addr : int
 	// see: https://planetscale.com/blog/generics-can-make-your-go-code-slower
 	// The maps below map from int in this trie to generic value type V
@@ -62,61 +69,680 @@ type TrieMap[V comparable] struct {
 	// and use the same key
 	keyToValue map[int]V
 	valueToKey map[V]int
+	// nextKey is the next unused key, keys are never reused.
+	nextKey int
+
+	// multimap is true if Insert adds to the set of values for a prefix
+	// rather than replacing it.
+	multimap bool
+	// less optionally selects the primary value of a prefix with multiple
+	// values.
+	less func(a, b V) bool
+
+	// writeThrough optionally holds hooks that mirror mutations to external
+	// storage, see SetWriteThrough.
+	writeThrough atomic.Pointer[writeThrough[V]]
+
+	// options the TrieMap was created with, so that derived maps can be
+	// configured the same way.
+	options options
+}
+
+// Entry is a prefix and its associated value.
+type Entry[V comparable] struct {
+	Prefix netip.Prefix `json:"prefix"`
+	Value  V            `json:"value"`
+}
+
+// Option configures optional behavior of a TrieMap.
+type Option func(*options)
+
+type options struct {
+	valueIndex       bool
+	nodePool         bool
+	preserveInserted bool
+	multimap         bool
+	// less is a func(a, b V) bool, it is stored as any as options are not
+	// generic.
+	less any
+}
+
+// WithValueIndex maintains a secondary index of the prefixes associated with
+// each value. This makes value scoped operations such as RemoveValue and
+// PrefixesFor proportional to the number of prefixes for the value rather than
+// the size of the trie, at the cost of additional memory.
+func WithValueIndex() Option {
+	return func(o *options) {
+		o.valueIndex = true
+	}
+}
+
+// WithNodePool recycles trie nodes freed by removals into subsequent inserts,
+// which reduces allocations and GC pressure for tables that are frequently
+// rebuilt. Nodes are reused as soon as they are removed from the trie.
+func WithNodePool() Option {
+	return func(o *options) {
+		o.nodePool = true
+	}
+}
+
+// WithPreserveInsertedPrefix records prefixes exactly as they were inserted,
+// including any host bits, so that they can be recovered when the TrieMap is
+// walked. By default prefixes are normalized to their masked form. Matching
+// and removal always use the masked form, so 10.1.2.3/8 and 10.0.0.0/8 refer
+// to the same entry either way.
+func WithPreserveInsertedPrefix() Option {
+	return func(o *options) {
+		o.preserveInserted = true
+	}
+}
+
+// WithMultimap enables multimap mode, in which each prefix is associated with
+// a set of values rather than a single value. In multimap mode:
+//
+//   - Insert adds the value to the prefix's set, rather than replacing it.
+//   - GetValues returns every value for the longest matching prefix, in the
+//     order they were inserted.
+//   - Get returns only the first value inserted for the prefix (or the best
+//     value if a comparator is configured, see WithLess).
+//   - Remove removes the prefix along with all of its values, whereas
+//     RemoveValue removes a single value from every prefix.
+func WithMultimap() Option {
+	return func(o *options) {
+		o.multimap = true
+	}
+}
+
+// WithLess sets a comparator used to pick the best of the values associated
+// with a single prefix, which only arises in multimap mode or with weighted
+// inserts. Get (and other methods returning a single value per prefix) then
+// return the least value according to less, rather than the first value
+// inserted. Ties are broken by insertion order.
+//
+// In the default single value mode Insert overwrites the value for a prefix,
+// so there is never more than one value to compare and less has no effect.
+//
+// The type of V must match that of the TrieMap or New will panic.
+func WithLess[V comparable](less func(a, b V) bool) Option {
+	return func(o *options) {
+		o.less = less
+	}
 }
 
 // New[V] returns a new, properly allocated TrieMap[V]
-func New[V comparable]() *TrieMap[V] {
-	return &TrieMap[V]{
+func New[V comparable](opts ...Option) *TrieMap[V] {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return newWithOptions[V](o)
+}
+
+// newWithOptions returns a new TrieMap[V] configured with the given options.
+func newWithOptions[V comparable](o options) *TrieMap[V] {
+	t := &TrieMap[V]{
 		keyToValue: make(map[int]V),
 		valueToKey: make(map[V]int),
+		multimap:   o.multimap,
+		options:    o,
+	}
+	if o.valueIndex {
+		t.trieMap.keyPrefixes = make(map[int][]netip.Prefix)
+	}
+	if o.nodePool {
+		t.trieMap.nodePool = &sync.Pool{New: func() any { return &trieNode{} }}
 	}
+	t.trieMap.preserveInserted = o.preserveInserted
+	if o.less != nil {
+		less, ok := o.less.(func(a, b V) bool)
+		if !ok {
+			panic("triemap: WithLess comparator does not match the value type")
+		}
+		t.less = less
+	}
+	return t
 }
 
 // Insert inserts value into TrieMap by index prefix.
 // You can later match a netip.Addr to value with Get().
+//
+// In multimap mode (see WithMultimap) the value is added to the set of values
+// for the prefix, rather than replacing it. Invalid prefixes are ignored.
 func (t *TrieMap[V]) Insert(prefix netip.Prefix, value V) {
+	if !prefix.IsValid() {
+		return
+	}
+
 	t.mu.Lock()
-	defer t.mu.Unlock()
+	key := t.keyFor(value)
+	if t.multimap {
+		t.trieMap.insertWeighted(prefix, key, 1)
+		// Report the primary value, which may not be the value inserted.
+		value = t.primary(t.trieMap.find(prefix))
+	} else {
+		t.insert(prefix, key)
+	}
+	t.mu.Unlock()
+
+	t.notifyInsert(prefix, value)
+}
+
+// InsertBatch inserts each of the entries into the TrieMap, as if by Insert,
+// while only acquiring the write lock once. This is considerably faster than
+// calling Insert repeatedly when loading a large number of prefixes. Entries
+// are inserted in order, so later entries replace earlier ones for the same
+// prefix (unless in multimap mode). Entries with invalid prefixes are
+// ignored.
+func (t *TrieMap[V]) InsertBatch(entries ...Entry[V]) {
+	invalid := func(e Entry[V]) bool { return !e.Prefix.IsValid() }
+	if slices.ContainsFunc(entries, invalid) {
+		entries = slices.DeleteFunc(slices.Clone(entries), invalid)
+	}
+
+	t.mu.Lock()
+	for _, e := range entries {
+		key := t.keyFor(e.Value)
+		if t.multimap {
+			t.trieMap.insertWeighted(e.Prefix, key, 1)
+		} else {
+			t.insert(e.Prefix, key)
+		}
+	}
+	if t.multimap && t.hooked() {
+		// Report the primary values, which may not be the values inserted.
+		prefixes := make([]netip.Prefix, len(entries))
+		for i, e := range entries {
+			prefixes[i] = e.Prefix
+		}
+		entries, _ = t.changes(prefixes)
+	}
+	t.mu.Unlock()
+
+	for _, e := range entries {
+		t.notifyInsert(e.Prefix, e.Value)
+	}
+}
+
+// InsertBoth inserts value into the TrieMap for both an IPv4 and an IPv6
+// prefix, eg. to apply the same policy to both address families. It returns
+// an error, without inserting either prefix, if v4 is not an IPv4 prefix or v6
+// is not an IPv6 prefix. IPv4-mapped IPv6 prefixes (eg. ::ffff:10.0.0.0/104)
+// are treated as, and inserted as, the equivalent IPv4 prefix.
+func (t *TrieMap[V]) InsertBoth(v4, v6 netip.Prefix, value V) error {
+	if v4.IsValid() && v4.Addr().Is4In6() && v4.Bits() >= 96 {
+		v4 = netip.PrefixFrom(v4.Addr().Unmap(), v4.Bits()-96)
+	}
+	if !v4.IsValid() || !v4.Addr().Is4() {
+		return fmt.Errorf("%s is not an IPv4 prefix", v4)
+	}
+	if !v6.IsValid() || v6.Addr().Unmap().Is4() {
+		return fmt.Errorf("%s is not an IPv6 prefix", v6)
+	}
+
+	t.InsertBatch(Entry[V]{Prefix: v4, Value: value}, Entry[V]{Prefix: v6, Value: value})
+	return nil
+}
 
+// InsertDefault inserts value into the TrieMap for both the IPv4 and IPv6
+// default routes (0.0.0.0/0 and ::/0).
+func (t *TrieMap[V]) InsertDefault(value V) {
+	t.InsertBatch(
+		Entry[V]{Prefix: netip.PrefixFrom(netip.IPv4Unspecified(), 0), Value: value},
+		Entry[V]{Prefix: netip.PrefixFrom(netip.IPv6Unspecified(), 0), Value: value},
+	)
+}
+
+// keyFor returns the key for value, allocating a new key if the value is not
+// yet present in the TrieMap.
+func (t *TrieMap[V]) keyFor(value V) int {
 	key, alreadyHave := t.valueToKey[value]
 	if !alreadyHave {
-		key = len(t.keyToValue)
+		key = t.nextKey
+		t.nextKey++
 		t.valueToKey[value] = key
 		t.keyToValue[key] = value
 	}
-	t.trieMap.insert(prefix, key)
+	return key
+}
+
+// primary returns the primary value of the prefix, that is the first enabled
+// value inserted or the least enabled value if a comparator is configured.
+func (t *TrieMap[V]) primary(v *nodeValue) V {
+	var best V
+	var found bool
+	for _, k := range v.keys {
+		if t.trieMap.isDisabled(k.key) {
+			continue
+		}
+		if value := t.keyToValue[k.key]; !found || (t.less != nil && t.less(value, best)) {
+			best, found = value, true
+		}
+	}
+	if !found {
+		// Every value is disabled, fall back to the first value inserted.
+		return t.keyToValue[v.key()]
+	}
+	return best
+}
+
+// copyValue copies the prefix and all of its values (along with their weights
+// and any expiry) from t into dst.
+func (t *TrieMap[V]) copyValue(dst *TrieMap[V], v *nodeValue) {
+	for _, k := range v.keys {
+		dst.trieMap.insertWeighted(v.prefix, dst.keyFor(t.keyToValue[k.key]), k.weight)
+	}
+	dst.trieMap.find(v.prefix).expires = v.expires
 }
 
 // Get returns the associated value for the matching prefix if any with
 // contains=true, or else the default value of V and contains=false.
 func (t *TrieMap[V]) Get(addr netip.Addr) (value V, contains bool) {
+	_, value, contains = t.GetPrefix(addr)
+	return
+}
+
+// GetPrefix is like Get but also returns the longest prefix that matched.
+func (t *TrieMap[V]) GetPrefix(addr netip.Addr) (prefix netip.Prefix, value V, contains bool) {
+	mu := t.mu.RLockAddr(addr)
+	defer mu.RUnlock()
+
+	if v := t.trieMap.get(addr); v != nil {
+		return v.prefix, t.primary(v), true
+	}
+	return
+}
+
+// LookupPrefix returns the value stored for exactly the given prefix, unlike
+// Get it does not match any covering prefixes. Prefixes are compared in their
+// masked form, so 10.1.2.3/8 finds 10.0.0.0/8.
+func (t *TrieMap[V]) LookupPrefix(prefix netip.Prefix) (value V, contains bool) {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
 
-	key, contains := t.trieMap.get(addr)
-	if contains {
-		value = t.keyToValue[key]
+	if v := t.trieMap.find(prefix); v != nil {
+		return t.primary(v), true
 	}
 	return
 }
 
+// Contains returns true if any prefix in the TrieMap contains addr. It is
+// cheaper than Get, as it stops at the first matching prefix rather than
+// searching for the longest.
+func (t *TrieMap[V]) Contains(addr netip.Addr) bool {
+	mu := t.mu.RLockAddr(addr)
+	defer mu.RUnlock()
+
+	return t.trieMap.contains(addr)
+}
+
+// GetAddrPort is like Get but accepts a netip.AddrPort, the port is ignored.
+func (t *TrieMap[V]) GetAddrPort(ap netip.AddrPort) (value V, contains bool) {
+	return t.Get(ap.Addr())
+}
+
+// GetAddrPortPrefix is like GetPrefix but accepts a netip.AddrPort, the port
+// is ignored.
+func (t *TrieMap[V]) GetAddrPortPrefix(ap netip.AddrPort) (prefix netip.Prefix, value V, contains bool) {
+	return t.GetPrefix(ap.Addr())
+}
+
+// GetWhere returns the most specific prefix containing addr whose value
+// satisfies pred, along with its value. It returns false if no matching prefix
+// has an acceptable value. The predicate is called with the read lock held, so
+// it must not call back into the TrieMap.
+func (t *TrieMap[V]) GetWhere(addr netip.Addr, pred func(V) bool) (prefix netip.Prefix, value V, contains bool) {
+	mu := t.mu.RLockAddr(addr)
+	defer mu.RUnlock()
+
+	t.trieMap.matches(addr, func(v *nodeValue) bool {
+		if candidate := t.primary(v); pred(candidate) {
+			prefix, value, contains = v.prefix, candidate, true
+		}
+		return true
+	})
+	return
+}
+
+// GetUpToLen is like GetPrefix but only considers prefixes no longer than
+// maxBits, eg. to ignore host routes and match only aggregates. It returns
+// false if no prefix within the limit contains addr.
+func (t *TrieMap[V]) GetUpToLen(addr netip.Addr, maxBits int) (prefix netip.Prefix, value V, contains bool) {
+	mu := t.mu.RLockAddr(addr)
+	defer mu.RUnlock()
+
+	t.trieMap.matches(addr, func(v *nodeValue) bool {
+		// Matches are visited from the least to the most specific.
+		if v.prefix.Bits() > maxBits {
+			return false
+		}
+		prefix, value, contains = v.prefix, t.primary(v), true
+		return true
+	})
+	return
+}
+
+// SameEntry returns true if both addresses resolve to the same longest
+// matching prefix (and thus the same value). It returns false if either
+// address has no matching prefix.
+func (t *TrieMap[V]) SameEntry(a, b netip.Addr) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	va := t.trieMap.get(a)
+	return va != nil && va == t.trieMap.get(b)
+}
+
 // Remove removes the prefix from the TrieMap.
 // Returns true if the prefix was removed, false if it was not found.
 func (t *TrieMap[V]) Remove(prefix netip.Prefix) bool {
 	t.mu.Lock()
-	defer t.mu.Unlock()
+	removed := t.remove(prefix)
+	t.mu.Unlock()
 
-	key, removed := t.trieMap.remove(prefix)
-	// If there are no more references to the key, remove the value.
-	if removed && t.trieMap.keyRefs[key] == 0 {
-		delete(t.keyToValue, key)
-		delete(t.valueToKey, t.keyToValue[key])
+	if removed {
+		t.notifyRemove(prefix)
 	}
 	return removed
 }
 
+// GetAndRemove finds the longest prefix matching addr and removes it,
+// returning the prefix and its value. The lookup and removal happen
+// atomically. Returns false if no prefix matches.
+func (t *TrieMap[V]) GetAndRemove(addr netip.Addr) (prefix netip.Prefix, value V, contains bool) {
+	t.mu.Lock()
+	v := t.trieMap.get(addr)
+	if v == nil {
+		t.mu.Unlock()
+		return
+	}
+	prefix, value = v.prefix, t.primary(v)
+	t.remove(prefix)
+	t.mu.Unlock()
+
+	t.notifyRemove(prefix)
+	return prefix, value, true
+}
+
+// insert inserts the key into the trie by prefix, replacing any existing keys,
+// and removes any replaced values that are no longer referenced from the bimap.
+func (t *TrieMap[V]) insert(prefix netip.Prefix, key int) {
+	prev := t.trieMap.insert(prefix, key)
+	if prev == nil {
+		return
+	}
+	for _, k := range prev.keys {
+		if t.trieMap.keyRefs[k.key] == 0 {
+			t.forget(k.key)
+		}
+	}
+}
+
+// remove removes the prefix from the trie, and any values that are no longer
+// referenced from the bimap.
+func (t *TrieMap[V]) remove(prefix netip.Prefix) bool {
+	v, removed := t.trieMap.remove(prefix)
+	if !removed {
+		return false
+	}
+	for _, k := range v.keys {
+		// If there are no more references to the key, remove the value.
+		if t.trieMap.keyRefs[k.key] == 0 {
+			t.forget(k.key)
+		}
+	}
+	return true
+}
+
 // RemoveValue removes all prefixes with the given value from the TrieMap.
 func (t *TrieMap[V]) RemoveValue(value V) {
+	t.mu.Lock()
+	key, contains := t.valueToKey[value]
+	if !contains {
+		t.mu.Unlock()
+		return
+	}
+
+	var prefixes []netip.Prefix
+	if t.hooked() {
+		prefixes = t.trieMap.prefixesFor(key)
+	}
+	t.trieMap.removeAll(key)
+	t.forget(key)
+	// In multimap mode, or with weighted values, a prefix may be left with
+	// other values.
+	inserted, removed := t.changes(prefixes)
+	t.mu.Unlock()
+
+	t.notifyAll(inserted, removed)
+}
+
+// RemoveIf removes every prefix for which pred returns true, returning the
+// number of prefixes removed. The matching prefixes are collected and removed
+// atomically under the write lock, so pred must not call back into the
+// TrieMap. Only the primary value of a weighted prefix is passed to pred.
+func (t *TrieMap[V]) RemoveIf(pred func(prefix netip.Prefix, value V) bool) int {
+	t.mu.Lock()
+	var prefixes []netip.Prefix
+	t.trieMap.walk(func(v *nodeValue) bool {
+		if pred(v.prefix, t.primary(v)) {
+			prefixes = append(prefixes, v.prefix)
+		}
+		return true
+	})
+	for _, prefix := range prefixes {
+		t.remove(prefix)
+	}
+	t.mu.Unlock()
+
+	for _, prefix := range prefixes {
+		t.notifyRemove(prefix)
+	}
+	return len(prefixes)
+}
+
+// forget removes a key that is no longer referenced by the trie, and its
+// value, from the bimap.
+func (t *TrieMap[V]) forget(key int) {
+	if value, ok := t.keyToValue[key]; ok {
+		delete(t.valueToKey, value)
+		delete(t.keyToValue, key)
+	}
+	delete(t.trieMap.disabled, key)
+}
+
+// Update calls fn for each prefix in the TrieMap, in ascending address order,
+// and replaces the prefix's value with the returned value. Prefixes for which
+// fn returns keep=false are removed. The whole update happens atomically under
+// the write lock, so fn must not call back into the TrieMap.
+//
+// Only the primary value of a weighted prefix is passed to fn, returning a
+// different value replaces all of the prefix's weighted values.
+func (t *TrieMap[V]) Update(fn func(prefix netip.Prefix, value V) (newValue V, keep bool)) {
+	var inserted []Entry[V]
+	var removed []netip.Prefix
+	defer func() { t.notifyAll(inserted, removed) }()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	hooked := t.hooked()
+	var values []*nodeValue
+	t.trieMap.walk(func(v *nodeValue) bool {
+		values = append(values, v)
+		return true
+	})
+
+	var released []int
+	for _, v := range values {
+		value := t.primary(v)
+		newValue, keep := fn(v.prefix, value)
+		if !keep {
+			t.trieMap.remove(v.prefix)
+			if hooked {
+				removed = append(removed, v.prefix)
+			}
+		} else if newValue != value {
+			t.trieMap.insert(v.prefix, t.keyFor(newValue))
+			if hooked {
+				inserted = append(inserted, Entry[V]{Prefix: v.prefix, Value: newValue})
+			}
+		} else {
+			continue
+		}
+		for _, k := range v.keys {
+			released = append(released, k.key)
+		}
+	}
+
+	// Values are only dropped once every prefix has been updated, so that a
+	// value released by one prefix can still be reused by a later one.
+	for _, key := range released {
+		if t.trieMap.keyRefs[key] == 0 {
+			t.forget(key)
+		}
+	}
+}
+
+// Replace replaces the value of exactly the given prefix, returning false if
+// the prefix is not present. Unlike removing and re-inserting the prefix, any
+// expiry (see InsertTTL) is kept. In multimap mode, or if weighted values have
+// been inserted, all of the prefix's values are replaced by the one value.
+//
+// It is named Replace, rather than Update, as Update already applies a
+// function to every prefix.
+func (t *TrieMap[V]) Replace(prefix netip.Prefix, value V) bool {
+	t.mu.Lock()
+	prev := t.trieMap.find(prefix)
+	if prev == nil {
+		t.mu.Unlock()
+		return false
+	}
+	t.insert(prefix, t.keyFor(value))
+	t.trieMap.find(prefix).expires = prev.expires
+	t.mu.Unlock()
+
+	t.notifyInsert(prefix, value)
+	return true
+}
+
+// Grow pre-sizes the TrieMap's internal maps to hold at least the given number
+// of additional prefixes and distinct values without rehashing, which speeds
+// up bulk loads. It is safe to call on a non-empty TrieMap.
+//
+// Trie nodes are allocated on demand, so the prefix hint is currently unused.
+func (t *TrieMap[V]) Grow(prefixes, distinctValues int) {
+	if distinctValues <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.keyToValue = growMap(t.keyToValue, distinctValues)
+	t.valueToKey = growMap(t.valueToKey, distinctValues)
+	t.trieMap.keyRefs = growMap(t.trieMap.keyRefs, distinctValues)
+	if t.trieMap.keyPrefixes != nil {
+		t.trieMap.keyPrefixes = growMap(t.trieMap.keyPrefixes, distinctValues)
+	}
+}
+
+// PrefixesFor returns all prefixes associated with the given value, in
+// ascending address order.
+func (t *TrieMap[V]) PrefixesFor(value V) []netip.Prefix {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	key, contains := t.valueToKey[value]
+	if !contains {
+		return nil
+	}
+	return t.trieMap.prefixesFor(key)
+}
+
+// Walk calls fn for each prefix in the TrieMap and its value, stopping early
+// if fn returns false. Prefixes are visited in a deterministic order, IPv4
+// before IPv6 and then in ascending address order, with shorter prefixes
+// before the longer prefixes they contain. The read lock is held for the
+// duration of the walk, so fn must not call back into the TrieMap.
+func (t *TrieMap[V]) Walk(fn func(prefix netip.Prefix, value V) bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	t.trieMap.walk(func(value *nodeValue) bool {
+		return fn(value.prefix, t.primary(value))
+	})
+}
+
+// All returns an iterator over every prefix in the TrieMap and its value, in
+// the same order as Walk. The read lock is acquired when iteration starts and
+// is held until the loop ends or breaks, so the loop body must not call
+// methods that modify the TrieMap, or it will deadlock.
+func (t *TrieMap[V]) All() iter.Seq2[netip.Prefix, V] {
+	return t.Walk
+}
+
+// LengthHistogram returns the number of prefixes stored at each prefix
+// length, separately for IPv4 and IPv6.
+func (t *TrieMap[V]) LengthHistogram() (v4 [33]int, v6 [129]int) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	t.trieMap.walk(func(value *nodeValue) bool {
+		if value.prefix.Addr().Is4() {
+			v4[value.prefix.Bits()]++
+		} else {
+			v6[value.prefix.Bits()]++
+		}
+		return true
+	})
+	return
+}
+
+// OnlyDefaultRoute returns true if no stored prefix, other than a default
+// route (a prefix of length 0), overlaps any part of the given prefix. That
+// is, every address in the prefix would only ever be matched by the default
+// route, if one is present.
+func (t *TrieMap[V]) OnlyDefaultRoute(prefix netip.Prefix) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	onlyDefault := true
+	t.trieMap.overlapping(prefix, func(value *nodeValue) bool {
+		onlyDefault = value.prefix.Bits() == 0
+		return onlyDefault
+	})
+	return onlyDefault
+}
+
+// Overlaps returns true if the given prefix overlaps any stored prefix, that
+// is if it equals, contains or is contained by a stored prefix. Prefixes of
+// different address families never overlap.
+func (t *TrieMap[V]) Overlaps(prefix netip.Prefix) bool {
+	if !prefix.IsValid() {
+		return false
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var overlaps bool
+	t.trieMap.overlapping(prefix, func(*nodeValue) bool {
+		overlaps = true
+		return false
+	})
+	return overlaps
+}
+
+// SetEnabled enables or disables all prefixes associated with the value.
+// Lookups (Get, GetPrefix, Lookup, GetAll and friends) skip disabled prefixes
+// and return the next best match instead, which is cheaper than removing and
+// re-inserting the prefixes to transiently drain a value. A prefix with
+// multiple values is only skipped once all of its values are disabled.
+//
+// Disabled prefixes still occupy the trie, they are counted by Len and are
+// visited by Walk. The disabled state is forgotten once the value has been
+// removed from every prefix, and has no effect on values not in the TrieMap.
+func (t *TrieMap[V]) SetEnabled(value V, enabled bool) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
@@ -124,9 +750,52 @@ func (t *TrieMap[V]) RemoveValue(value V) {
 	if !contains {
 		return
 	}
-	t.trieMap.removeAll(key)
-	delete(t.keyToValue, key)
-	delete(t.valueToKey, value)
+
+	if enabled {
+		delete(t.trieMap.disabled, key)
+		return
+	}
+	if t.trieMap.disabled == nil {
+		t.trieMap.disabled = make(map[int]struct{})
+	}
+	t.trieMap.disabled[key] = struct{}{}
+}
+
+// Len returns the number of prefixes in the TrieMap.
+func (t *TrieMap[V]) Len() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.trieMap.len
+}
+
+// ValueLen returns the number of distinct values in the TrieMap, that is the
+// number of values referenced by at least one prefix.
+func (t *TrieMap[V]) ValueLen() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return len(t.keyToValue)
+}
+
+// Clear removes every prefix and value from the TrieMap, leaving it in the
+// same state as a newly created TrieMap with the same options. If node
+// pooling is enabled, the nodes are recycled for subsequent inserts.
+func (t *TrieMap[V]) Clear() {
+	t.mu.Lock()
+	var removed []netip.Prefix
+	if t.hooked() {
+		t.trieMap.walk(func(value *nodeValue) bool {
+			removed = append(removed, value.prefix)
+			return true
+		})
+	}
+	t.trieMap.clear()
+	t.keyToValue = make(map[int]V)
+	t.valueToKey = make(map[V]int)
+	t.mu.Unlock()
+
+	t.notifyAll(nil, removed)
 }
 
 // Empty returns true if the TrieMap is empty.
@@ -146,203 +815,633 @@ type trieMap struct {
 	ipv4Root *trieNode
 	ipv6Root *trieNode
 	keyRefs  map[int]int
+	// keyPrefixes is an optional index of the prefixes associated with each
+	// key, it is nil unless the value index is enabled.
+	keyPrefixes map[int][]netip.Prefix
+	// nodePool is an optional pool of recycled trie nodes, it is nil unless
+	// node pooling is enabled.
+	nodePool *sync.Pool
+	// preserveInserted stores prefixes as inserted rather than masked.
+	preserveInserted bool
+	// len is the number of nodes with a value.
+	len int
+	// disabled is the set of keys that lookups skip, it is nil until a value
+	// is first disabled.
+	disabled map[int]struct{}
 }
 
+// trieNode is a node in a path compressed (PATRICIA) trie. Rather than having
+// a node for every bit of every prefix, nodes are only stored for prefixes
+// with a value and at the points where prefixes diverge. The bits skipped
+// between a node and its parent are recorded in the node itself, as the
+// masked prefix it represents.
 type trieNode struct {
 	child0, child1 *trieNode
 	value          *nodeValue
+	// addr holds the leading bits of the prefix represented by the node, its
+	// remaining bits are zero.
+	addr uint128.Uint128
+	// bits is the length of the prefix represented by the node, the root
+	// node always has a length of 0.
+	bits int
+}
+
+// child returns the child of the node selected by the given bit.
+func (n *trieNode) child(bit bool) *trieNode {
+	if bit {
+		return n.child1
+	}
+	return n.child0
+}
+
+// setChild replaces the child of the node selected by the given bit.
+func (n *trieNode) setChild(bit bool, child *trieNode) {
+	if bit {
+		n.child1 = child
+	} else {
+		n.child0 = child
+	}
+}
+
+// replaceChild replaces the given child of the node with another node.
+func (n *trieNode) replaceChild(old, new *trieNode) {
+	if n.child0 == old {
+		n.child0 = new
+	} else {
+		n.child1 = new
+	}
+}
+
+// matches returns true if the leading bits of ip match the node's prefix.
+func (n *trieNode) matches(ip uint128.Uint128, totalBits int) bool {
+	return addrbits.CommonLen(n.addr, ip, totalBits) >= n.bits
 }
 
 type nodeValue struct {
+	// prefix is the masked prefix, or the prefix as inserted if inserted
+	// prefixes are preserved. The node's position in the trie always
+	// corresponds to the masked prefix.
 	prefix netip.Prefix
+	// keys holds the keys associated with the prefix. There is ordinarily
+	// exactly one, multiple keys are only stored by weighted inserts.
+	keys []weightedKey
+	// inline backs keys while there is only one key, to save a separate
+	// allocation per value. keys spills to the heap once a second key is
+	// added.
+	inline [1]weightedKey
+	// expires is the time after which the prefix is removed by ExpireNow, or
+	// the zero time if the prefix never expires.
+	expires time.Time
+}
+
+type weightedKey struct {
 	key    int
+	weight uint32
 }
 
-func (t *trieMap) get(addr netip.Addr) (key int, contains bool) {
-	root := t.getRootNode(addr)
-	if root == nil {
-		return -1, false
+// newNodeValue returns a value for the prefix without any keys. The first key
+// appended to it is stored inline.
+func newNodeValue(prefix netip.Prefix, expires time.Time) *nodeValue {
+	v := &nodeValue{prefix: prefix, expires: expires}
+	v.keys = v.inline[:0]
+	return v
+}
+
+// key returns the primary (first inserted) key of the value.
+func (v *nodeValue) key() int {
+	return v.keys[0].key
+}
+
+// hasKey returns true if the value contains the given key.
+func (v *nodeValue) hasKey(key int) bool {
+	return slices.ContainsFunc(v.keys, func(k weightedKey) bool { return k.key == key })
+}
+
+// get returns the value of the longest enabled prefix containing addr, or nil
+// if there is no such prefix.
+func (t *trieMap) get(addr netip.Addr) (value *nodeValue) {
+	if !addr.IsValid() {
+		return nil
 	}
-	curr := root
 
-	// Maybe the root node matches.
-	var longestMatchLength int = -1
-	if curr.value != nil && curr.value.prefix.Contains(addr) {
-		longestMatchLength = curr.value.prefix.Bits()
-		key = curr.value.key
-		contains = true
+	// The address is only converted once, containment is then checked by
+	// comparing its leading bits against each node's prefix.
+	ip, totalBits := addrbits.FromAddr(addr)
+	curr := t.rootFor(totalBits)
+	// Every prefix on the path is longer than the last, so the final match
+	// is the longest.
+	for curr != nil && curr.matches(ip, totalBits) {
+		if curr.value != nil && t.isEnabled(curr.value) {
+			value = curr.value
+		}
+		if curr.bits == totalBits {
+			break
+		}
+		curr = curr.child(bitAt(ip, totalBits, curr.bits))
 	}
+	return
+}
 
-	ip, totalBits := addrToUint128(addr)
-	for i := totalBits - 1; i >= 0; i-- {
-		if ip.Bit(i) {
-			if curr.child1 != nil {
-				curr = curr.child1
-			} else {
-				break
-			}
-		} else {
-			if curr.child0 != nil {
-				curr = curr.child0
-			} else {
-				break
-			}
+// contains returns true if any enabled prefix contains addr. Unlike get, it
+// stops at the first (least specific) match.
+func (t *trieMap) contains(addr netip.Addr) bool {
+	if !addr.IsValid() {
+		return false
+	}
+
+	ip, totalBits := addrbits.FromAddr(addr)
+	curr := t.rootFor(totalBits)
+	for curr != nil && curr.matches(ip, totalBits) {
+		if curr.value != nil && t.isEnabled(curr.value) {
+			return true
 		}
+		if curr.bits == totalBits {
+			break
+		}
+		curr = curr.child(bitAt(ip, totalBits, curr.bits))
+	}
+	return false
+}
 
-		// check for a match in the current node.
-		if curr.value != nil && curr.value.prefix.Contains(addr) {
-			if curr.value.prefix.Bits() > longestMatchLength {
-				longestMatchLength = curr.value.prefix.Bits()
-				key = curr.value.key
-				contains = true
-			}
+// matches calls fn for the value of each prefix containing addr, from the
+// least to the most specific, skipping any prefixes that are disabled.
+// Iteration stops early if fn returns false.
+func (t *trieMap) matches(addr netip.Addr, fn func(value *nodeValue) bool) {
+	if !addr.IsValid() {
+		return
+	}
+
+	ip, totalBits := addrbits.FromAddr(addr)
+	curr := t.rootFor(totalBits)
+	for curr != nil && curr.matches(ip, totalBits) {
+		if curr.value != nil && t.isEnabled(curr.value) && !fn(curr.value) {
+			return
 		}
+		if curr.bits == totalBits {
+			return
+		}
+		curr = curr.child(bitAt(ip, totalBits, curr.bits))
 	}
+}
 
-	return
+// find returns the value stored for exactly the given prefix, or nil if the
+// prefix is not present.
+func (t *trieMap) find(prefix netip.Prefix) *nodeValue {
+	if !prefix.IsValid() {
+		return nil
+	}
+
+	curr := t.getRootNode(prefix.Addr())
+	ip, totalBits := addrbits.FromAddr(prefix.Addr())
+	bits := prefix.Bits()
+	for curr != nil && curr.bits < bits {
+		curr = curr.child(bitAt(ip, totalBits, curr.bits))
+	}
+	if curr == nil || curr.bits != bits || !curr.matches(ip, totalBits) {
+		return nil
+	}
+	// A node only ever holds a value for the masked prefix it represents.
+	return curr.value
 }
 
-// insert handles inserting keys into the trie based on prefix.
-func (t *trieMap) insert(prefix netip.Prefix, key int) {
+// insert handles inserting keys into the trie based on prefix, returning the
+// value it replaced, if any. Invalid prefixes are ignored.
+func (t *trieMap) insert(prefix netip.Prefix, key int) *nodeValue {
+	if !prefix.IsValid() {
+		return nil
+	}
+
+	prefix = t.normalize(prefix)
+	curr := t.node(prefix)
+	prev := curr.value
+	if curr.value != nil {
+		for _, k := range curr.value.keys {
+			t.release(curr.value.prefix, k.key)
+		}
+	} else {
+		t.len++
+	}
+	t.retain(prefix, key)
+
+	curr.value = newNodeValue(prefix, time.Time{})
+	curr.value.keys = append(curr.value.keys, weightedKey{key: key, weight: 1})
+	return prev
+}
+
+// insertWeighted adds the key to the set of keys associated with the prefix,
+// or updates its weight if it is already present. Invalid prefixes are
+// ignored.
+func (t *trieMap) insertWeighted(prefix netip.Prefix, key int, weight uint32) {
+	if !prefix.IsValid() {
+		return
+	}
+
+	prefix = t.normalize(prefix)
+	curr := t.node(prefix)
+	if curr.value == nil {
+		curr.value = newNodeValue(prefix, time.Time{})
+		t.len++
+	} else if curr.value.prefix != prefix {
+		// The preserved prefix differs only in its host bits, record the
+		// latest one.
+		for _, k := range curr.value.keys {
+			t.unindex(curr.value.prefix, k.key)
+			t.index(prefix, k.key)
+		}
+		prev := curr.value
+		curr.value = newNodeValue(prefix, prev.expires)
+		curr.value.keys = append(curr.value.keys, prev.keys...)
+	}
+
+	for i := range curr.value.keys {
+		if curr.value.keys[i].key == key {
+			curr.value.keys[i].weight = weight
+			return
+		}
+	}
+	t.retain(prefix, key)
+	curr.value.keys = append(curr.value.keys, weightedKey{key: key, weight: weight})
+}
+
+// node returns the node for the given prefix, creating it (and any branching
+// node) if necessary.
+func (t *trieMap) node(prefix netip.Prefix) *trieNode {
 	root := t.getRootNode(prefix.Addr())
 	if root == nil {
-		if prefix.Addr().Unmap().Is4() {
-			t.ipv4Root = &trieNode{}
-			root = t.ipv4Root
+		root = t.newNode(uint128.Zero, 0)
+		if prefix.Addr().Is4() {
+			t.ipv4Root = root
 		} else {
-			t.ipv6Root = &trieNode{}
-			root = t.ipv6Root
+			t.ipv6Root = root
 		}
 	}
-	curr := root
-	ip, totalBits := addrToUint128(prefix.Addr())
+
+	ip, totalBits := addrbits.FromAddr(prefix.Addr())
 	bits := prefix.Bits()
-	for i := totalBits - 1; i >= totalBits-bits; i-- {
-		if ip.Bit(i) {
-			if curr.child1 == nil {
-				curr.child1 = &trieNode{}
-			}
-			curr = curr.child1
-		} else {
-			if curr.child0 == nil {
-				curr.child0 = &trieNode{}
-			}
-			curr = curr.child0
+	ip = maskBits(ip, totalBits, bits)
+
+	parent := root
+	for parent.bits < bits {
+		bit := bitAt(ip, totalBits, parent.bits)
+		child := parent.child(bit)
+		if child == nil {
+			child = t.newNode(ip, bits)
+			parent.setChild(bit, child)
+			return child
 		}
-	}
 
-	if curr.value != nil {
-		t.keyRefs[curr.value.key]--
-	}
-	if t.keyRefs == nil {
-		t.keyRefs = make(map[int]int)
-	}
-	t.keyRefs[key]++
+		common := min(addrbits.CommonLen(child.addr, ip, totalBits), child.bits, bits)
+		if common == child.bits {
+			// The child is the prefix, or one of its ancestors.
+			parent = child
+			continue
+		}
 
-	curr.value = &nodeValue{prefix: prefix, key: key}
+		// The child diverges from the prefix, so a node is needed where they
+		// diverge. If the prefix is an ancestor of the child, that node is the
+		// prefix itself, otherwise a branching node holds them both.
+		branch := t.newNode(maskBits(ip, totalBits, common), common)
+		branch.setChild(bitAt(child.addr, totalBits, common), child)
+		parent.setChild(bit, branch)
+		if common == bits {
+			return branch
+		}
+		curr := t.newNode(ip, bits)
+		branch.setChild(bitAt(ip, totalBits, common), curr)
+		return curr
+	}
+	return parent
 }
 
 // remove handles removing keys from the trie based on prefix.
-func (t *trieMap) remove(prefix netip.Prefix) (int, bool) {
-	var stack []*trieNode
-	root := t.getRootNode(prefix.Addr())
-	if root == nil {
-		return -1, false
+func (t *trieMap) remove(prefix netip.Prefix) (*nodeValue, bool) {
+	return t.removeKeys(prefix, func(int) bool { return true })
+}
+
+// removeKey removes a single key from the prefix, the prefix itself is only
+// removed once it has no keys left.
+func (t *trieMap) removeKey(prefix netip.Prefix, key int) bool {
+	_, removed := t.removeKeys(prefix, func(k int) bool { return k == key })
+	return removed
+}
+
+// removeKeys removes the keys matching the predicate from the prefix, pruning
+// the node if it has no keys left. It returns the previous value of the node.
+// The node is found by its position in the trie, so any host bits set in the
+// prefix are ignored, matching the normalization applied on insert.
+func (t *trieMap) removeKeys(prefix netip.Prefix, pred func(key int) bool) (*nodeValue, bool) {
+	if !prefix.IsValid() {
+		return nil, false
 	}
-	curr := root
+
+	curr := t.getRootNode(prefix.Addr())
+	ip, totalBits := addrbits.FromAddr(prefix.Addr())
 	bits := prefix.Bits()
-	ip, totalBits := addrToUint128(prefix.Addr())
-	for i := totalBits - 1; i >= totalBits-bits; i-- {
+
+	var stack []*trieNode
+	for curr != nil && curr.bits < bits {
 		stack = append(stack, curr)
-		if ip.Bit(i) {
-			curr = curr.child1
+		curr = curr.child(bitAt(ip, totalBits, curr.bits))
+	}
+	if curr == nil || curr.bits != bits || !curr.matches(ip, totalBits) || curr.value == nil {
+		return nil, false
+	}
+	stack = append(stack, curr)
+
+	prev := curr.value
+	if !slices.ContainsFunc(prev.keys, func(k weightedKey) bool { return pred(k.key) }) {
+		return nil, false
+	}
+
+	value := newNodeValue(prev.prefix, prev.expires)
+	for _, k := range prev.keys {
+		if pred(k.key) {
+			t.release(prev.prefix, k.key)
 		} else {
-			curr = curr.child0
-		}
-		if curr == nil {
-			return -1, false
+			value.keys = append(value.keys, k)
 		}
 	}
-	stack = append(stack, curr)
-	if curr.value != nil && curr.value.prefix == prefix {
-		key := curr.value.key
+
+	if len(value.keys) > 0 {
+		curr.value = value
+	} else {
 		curr.value = nil
-		t.keyRefs[key]--
-		if t.keyRefs[key] == 0 {
-			delete(t.keyRefs, key)
-		}
-		prune(stack)
-		return key, true
+		t.len--
+		t.prune(stack)
 	}
-	return -1, false
+	return prev, true
 }
 
-// removeAll removes all nodes with the given key.
+// removeAll removes the given key from all nodes.
 func (t *trieMap) removeAll(key int) {
+	for _, prefix := range t.prefixesFor(key) {
+		t.removeKey(prefix, key)
+	}
+}
+
+// retain records a reference from the prefix to the key.
+func (t *trieMap) retain(prefix netip.Prefix, key int) {
+	if t.keyRefs == nil {
+		t.keyRefs = make(map[int]int)
+	}
+	t.keyRefs[key]++
+	t.index(prefix, key)
+}
+
+// release drops a reference from the prefix to the key.
+func (t *trieMap) release(prefix netip.Prefix, key int) {
+	t.keyRefs[key]--
+	if t.keyRefs[key] == 0 {
+		delete(t.keyRefs, key)
+	}
+	t.unindex(prefix, key)
+}
+
+// prefixesFor returns all prefixes with the given key, in ascending address
+// order.
+func (t *trieMap) prefixesFor(key int) []netip.Prefix {
+	if t.keyPrefixes != nil {
+		prefixes := slices.Clone(t.keyPrefixes[key])
+		slices.SortFunc(prefixes, comparePrefixes)
+		return prefixes
+	}
+
 	var prefixes []netip.Prefix
+	t.walk(func(value *nodeValue) bool {
+		if value.hasKey(key) {
+			prefixes = append(prefixes, value.prefix)
+		}
+		return true
+	})
+	return prefixes
+}
+
+// walk calls fn for each value in the trie in ascending address order, IPv4
+// before IPv6. Iteration stops early if fn returns false.
+func (t *trieMap) walk(fn func(value *nodeValue) bool) {
+	t.walkNodes(fn, t.ipv4Root, t.ipv6Root)
+}
 
-	// Traverse the trie to find all prefixes with the given key.
+// walkNodes calls fn for each value in the subtrees rooted at the given nodes
+// (which may be nil), in ascending address order. It returns false if fn
+// stopped the iteration early.
+func (t *trieMap) walkNodes(fn func(value *nodeValue) bool, roots ...*trieNode) bool {
 	var stack []*trieNode
-	if t.ipv4Root != nil {
-		stack = append(stack, t.ipv4Root)
-	}
-	if t.ipv6Root != nil {
-		stack = append(stack, t.ipv6Root)
+	for _, root := range slices.Backward(roots) {
+		if root != nil {
+			stack = append(stack, root)
+		}
 	}
 	for len(stack) > 0 {
 		curr := stack[len(stack)-1]
 		stack = stack[:len(stack)-1]
-		if curr.value != nil && curr.value.key == key {
-			prefixes = append(prefixes, curr.value.prefix)
+		if curr.value != nil && !fn(curr.value) {
+			return false
+		}
+		// Push child1 first so that child0 is visited first.
+		if curr.child1 != nil {
+			stack = append(stack, curr.child1)
 		}
 		if curr.child0 != nil {
 			stack = append(stack, curr.child0)
 		}
-		if curr.child1 != nil {
-			stack = append(stack, curr.child1)
+	}
+	return true
+}
+
+// overlapping calls fn for each value whose prefix overlaps the given prefix,
+// that is every value containing the prefix (shortest first) followed by
+// every value contained by it, in ascending address order. Iteration stops
+// early if fn returns false.
+func (t *trieMap) overlapping(prefix netip.Prefix, fn func(value *nodeValue) bool) {
+	if !prefix.IsValid() {
+		return
+	}
+
+	curr := t.getRootNode(prefix.Addr())
+	ip, totalBits := addrbits.FromAddr(prefix.Addr())
+	bits := prefix.Bits()
+	for curr != nil && curr.bits < bits {
+		if !curr.matches(ip, totalBits) {
+			return
+		}
+		if curr.value != nil && !fn(curr.value) {
+			return
 		}
+		curr = curr.child(bitAt(ip, totalBits, curr.bits))
 	}
+	if curr != nil && addrbits.CommonLen(curr.addr, ip, totalBits) >= bits {
+		t.walkNodes(fn, curr)
+	}
+}
 
-	for _, prefix := range prefixes {
-		t.remove(prefix)
+// index adds the prefix to the value index (if enabled).
+func (t *trieMap) index(prefix netip.Prefix, key int) {
+	if t.keyPrefixes == nil {
+		return
+	}
+	t.keyPrefixes[key] = append(t.keyPrefixes[key], prefix)
+}
+
+// unindex removes the prefix from the value index (if enabled).
+func (t *trieMap) unindex(prefix netip.Prefix, key int) {
+	if t.keyPrefixes == nil {
+		return
+	}
+	prefixes := t.keyPrefixes[key]
+	if i := slices.Index(prefixes, prefix); i >= 0 {
+		prefixes = slices.Delete(prefixes, i, i+1)
+	}
+	if len(prefixes) == 0 {
+		delete(t.keyPrefixes, key)
+	} else {
+		t.keyPrefixes[key] = prefixes
+	}
+}
+
+// normalize returns the form of the prefix that is stored in the trie.
+func (t *trieMap) normalize(prefix netip.Prefix) netip.Prefix {
+	if t.preserveInserted {
+		return prefix
 	}
+	return prefix.Masked()
+}
+
+// isDisabled returns true if the key has been disabled.
+func (t *trieMap) isDisabled(key int) bool {
+	_, disabled := t.disabled[key]
+	return disabled
+}
+
+// isEnabled returns true if any of the value's keys are enabled.
+func (t *trieMap) isEnabled(value *nodeValue) bool {
+	if len(t.disabled) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(value.keys, func(k weightedKey) bool { return !t.isDisabled(k.key) })
 }
 
 // getRootNode selects the root node based on the IP type.
 func (t *trieMap) getRootNode(addr netip.Addr) *trieNode {
-	if addr.Unmap().Is4() {
+	if addr.Is4() {
 		return t.ipv4Root
 	} else {
 		return t.ipv6Root
 	}
 }
 
-// prune checks nodes from the bottom up to remove any that are no longer needed.
-func prune(stack []*trieNode) {
-	for i := len(stack) - 1; i >= 0; i-- {
-		node := stack[i]
-		if node.child0 == nil && node.child1 == nil && node.value == nil {
-			if i > 0 { // Check if not root
-				parent := stack[i-1]
-				if parent.child0 == node {
-					parent.child0 = nil
-				} else {
-					parent.child1 = nil
-				}
+// rootFor returns the root node for addresses of totalBits.
+func (t *trieMap) rootFor(totalBits int) *trieNode {
+	if totalBits == 32 {
+		return t.ipv4Root
+	}
+	return t.ipv6Root
+}
+
+// prune removes the last node of the stack (the path from the root to the
+// node) if it no longer holds a value, along with its parent if that was only
+// needed to branch to the node. Nodes that still branch are kept.
+func (t *trieMap) prune(stack []*trieNode) {
+	node := stack[len(stack)-1]
+	if node.value != nil || len(stack) == 1 {
+		// The root node is never removed.
+		return
+	}
+	parent := stack[len(stack)-2]
+
+	switch {
+	case node.child0 != nil && node.child1 != nil:
+		// Still needed to branch.
+		return
+	case node.child0 != nil:
+		parent.replaceChild(node, node.child0)
+	case node.child1 != nil:
+		parent.replaceChild(node, node.child1)
+	default:
+		parent.replaceChild(node, nil)
+		// The parent may have only been needed to branch to the node.
+		if len(stack) > 2 && parent.value == nil {
+			sibling := parent.child0
+			if sibling == nil {
+				sibling = parent.child1
 			}
-		} else {
-			break
+			stack[len(stack)-3].replaceChild(parent, sibling)
+			t.freeNode(parent)
 		}
 	}
+	t.freeNode(node)
+}
+
+// clear removes all nodes and references from the trie.
+func (t *trieMap) clear() {
+	if t.nodePool != nil {
+		stack := []*trieNode{t.ipv4Root, t.ipv6Root}
+		for len(stack) > 0 {
+			curr := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if curr == nil {
+				continue
+			}
+			stack = append(stack, curr.child0, curr.child1)
+			t.freeNode(curr)
+		}
+	}
+
+	t.ipv4Root, t.ipv6Root = nil, nil
+	t.keyRefs = nil
+	if t.keyPrefixes != nil {
+		t.keyPrefixes = make(map[int][]netip.Prefix)
+	}
+	t.len = 0
+	t.disabled = nil
 }
 
-// addrToUint128 converts a netip.Addr into a uint128.Uint128 for easy bit manipulation.
-// It returns the uint128 and the total number of bits for the given address type.
-func addrToUint128(addr netip.Addr) (uint128.Uint128, int) {
-	if addr.Unmap().Is4() {
-		ip4 := addr.As4()
-		return uint128.From64(uint64(binary.BigEndian.Uint32(ip4[:]))), 32
+// newNode allocates a new trie node for the prefix of the given length, reusing
+// a recycled node if the node pool is enabled.
+func (t *trieMap) newNode(addr uint128.Uint128, bits int) *trieNode {
+	var node *trieNode
+	if t.nodePool != nil {
+		node = t.nodePool.Get().(*trieNode)
+	} else {
+		node = &trieNode{}
+	}
+	node.addr, node.bits = addr, bits
+	return node
+}
+
+// freeNode recycles a node that is no longer referenced by the trie (if the
+// node pool is enabled).
+func (t *trieMap) freeNode(node *trieNode) {
+	if t.nodePool != nil {
+		// Reset the node so no stale children or values are handed out.
+		*node = trieNode{}
+		t.nodePool.Put(node)
 	}
-	ip6 := addr.As16()
-	return uint128.New(binary.BigEndian.Uint64(ip6[8:]), binary.BigEndian.Uint64(ip6[:8])), 128
+}
+
+// bitAt returns the bit of ip at the given index, counting from the most
+// significant bit of an address of totalBits.
+func bitAt(ip uint128.Uint128, totalBits, i int) bool {
+	return ip.Bit(totalBits - 1 - i)
+}
+
+// maskBits clears all but the leading bits of ip, an address of totalBits.
+func maskBits(ip uint128.Uint128, totalBits, bits int) uint128.Uint128 {
+	return ip.Rsh(uint(totalBits - bits)).Lsh(uint(totalBits - bits))
+}
+
+// comparePrefixes orders prefixes by address and then by prefix length, which
+// matches the order in which the trie is walked.
+func comparePrefixes(a, b netip.Prefix) int {
+	if c := a.Masked().Addr().Compare(b.Masked().Addr()); c != 0 {
+		return c
+	}
+	return a.Bits() - b.Bits()
+}
+
+// growMap returns a copy of m with capacity for at least n additional entries.
+func growMap[K comparable, V any](m map[K]V, n int) map[K]V {
+	grown := make(map[K]V, len(m)+n)
+	maps.Copy(grown, m)
+	return grown
 }
@@ -48,8 +48,11 @@
 package triemap_test
 
 import (
+	"math/rand/v2"
 	"net/netip"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/noisysockets/util/triemap"
 	"github.com/stretchr/testify/require"
@@ -181,6 +184,56 @@ func TestTrieMapRemove(t *testing.T) {
 	require.False(t, contains)
 }
 
+func TestTrieMapRemoveHostBits(t *testing.T) {
+	trieMap := triemap.New[string]()
+
+	// Prefixes are normalized on insert, so equivalent prefixes are treated
+	// identically regardless of their host bits.
+	trieMap.Insert(netip.MustParsePrefix("192.95.5.65/27"), "a")
+	require.Equal(t, []netip.Prefix{netip.MustParsePrefix("192.95.5.64/27")}, trieMap.PrefixesFor("a"))
+
+	require.True(t, trieMap.Remove(netip.MustParsePrefix("192.95.5.64/27")))
+	_, contains := trieMap.Get(netip.MustParseAddr("192.95.5.68"))
+	require.False(t, contains)
+	require.True(t, trieMap.Empty())
+
+	// And the other way around.
+	trieMap.Insert(netip.MustParsePrefix("192.95.5.64/27"), "a")
+	require.True(t, trieMap.Remove(netip.MustParsePrefix("192.95.5.65/27")))
+	require.True(t, trieMap.Empty())
+
+	require.NoError(t, trieMap.Validate())
+}
+
+func TestTrieMapRemoveReinsert(t *testing.T) {
+	trieMap := triemap.New[string](triemap.WithValueIndex())
+	// The zero value shares the bimap with the other values.
+	trieMap.Insert(netip.MustParsePrefix("192.168.0.0/16"), "")
+
+	prefix := netip.MustParsePrefix("10.0.0.0/8")
+	for i := 0; i < 3; i++ {
+		trieMap.Insert(prefix, "a")
+		require.NoError(t, trieMap.Validate())
+
+		// Removing the last prefix for the value drops it from the bimap,
+		// without disturbing any other value.
+		require.True(t, trieMap.Remove(prefix))
+		require.NoError(t, trieMap.Validate())
+		require.Empty(t, trieMap.PrefixesFor("a"))
+
+		value, ok := trieMap.Get(netip.MustParseAddr("192.168.1.1"))
+		require.True(t, ok)
+		require.Equal(t, "", value)
+	}
+
+	trieMap.Insert(prefix, "a")
+	value, ok := trieMap.Get(netip.MustParseAddr("10.1.2.3"))
+	require.True(t, ok)
+	require.Equal(t, "a", value)
+	require.Equal(t, []netip.Prefix{prefix}, trieMap.PrefixesFor("a"))
+	require.NoError(t, trieMap.Validate())
+}
+
 func TestTrieMapIPv4(t *testing.T) {
 	trieMap := triemap.New[string]()
 
@@ -326,3 +379,1088 @@ func TestTrieMapIPv6(t *testing.T) {
 	value, _ = trieMap.Get(netip.MustParseAddr("2404:6800:4004:800:dead:beef:dead:beef"))
 	require.Equal(t, "a", value)
 }
+
+func TestTrieMapPrefixesFor(t *testing.T) {
+	for _, indexed := range []bool{false, true} {
+		name := "Walk"
+		var opts []triemap.Option
+		if indexed {
+			name = "Indexed"
+			opts = append(opts, triemap.WithValueIndex())
+		}
+
+		t.Run(name, func(t *testing.T) {
+			trieMap := triemap.New[string](opts...)
+			for value, prefixes := range testPrefixes {
+				for _, prefix := range prefixes {
+					trieMap.Insert(prefix, value)
+				}
+			}
+
+			require.Equal(t, []netip.Prefix{
+				netip.MustParsePrefix("52.94.76.0/22"),
+				netip.MustParsePrefix("2600:1f01:4874::/47"),
+			}, trieMap.PrefixesFor("us-west-2"))
+
+			require.Nil(t, trieMap.PrefixesFor("unknown"))
+
+			// Overwriting a prefix should move it to the new value.
+			trieMap.Insert(netip.MustParsePrefix("52.94.76.0/22"), "eu-west-3")
+			require.Equal(t, []netip.Prefix{
+				netip.MustParsePrefix("2600:1f01:4874::/47"),
+			}, trieMap.PrefixesFor("us-west-2"))
+			require.Contains(t, trieMap.PrefixesFor("eu-west-3"), netip.MustParsePrefix("52.94.76.0/22"))
+
+			require.True(t, trieMap.Remove(netip.MustParsePrefix("2600:1f01:4874::/47")))
+			require.Empty(t, trieMap.PrefixesFor("us-west-2"))
+
+			trieMap.RemoveValue("eu-west-3")
+			require.Empty(t, trieMap.PrefixesFor("eu-west-3"))
+
+			_, contains := trieMap.Get(netip.MustParseAddr("52.94.76.1"))
+			require.False(t, contains)
+
+			_, contains = trieMap.Get(netip.MustParseAddr("35.180.1.1"))
+			require.False(t, contains)
+		})
+	}
+}
+
+// benchmarkPrefixes returns a synthetic AWS IP ranges style dataset, a mix of
+// IPv4 aggregates and host routes, and IPv6 aggregates and host routes.
+func benchmarkPrefixes() map[netip.Prefix]int {
+	prefixes := make(map[netip.Prefix]int)
+	for i := 0; i < 10000; i++ {
+		prefixes[netip.PrefixFrom(netip.AddrFrom4([4]byte{52, byte(i >> 8), byte(i), 0}), 16+i%9)] = i % 32
+		prefixes[netip.PrefixFrom(netip.AddrFrom4([4]byte{35, byte(i >> 8), byte(i), byte(i * 7)}), 32)] = i % 32
+		prefixes[netip.PrefixFrom(netip.AddrFrom16([16]byte{0x26, 0x00, 0x1f, byte(i >> 8), byte(i)}), 40+i%17)] = i % 32
+		prefixes[netip.PrefixFrom(netip.AddrFrom16([16]byte{0x24, 0x00, 0x65, 0, 0, byte(i >> 8), byte(i), 15: byte(i * 7)}), 128)] = i % 32
+	}
+	return prefixes
+}
+
+func BenchmarkTrieMapInsert(b *testing.B) {
+	prefixes := benchmarkPrefixes()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		trieMap := triemap.New[int]()
+		for prefix, value := range prefixes {
+			trieMap.Insert(prefix, value)
+		}
+	}
+}
+
+func BenchmarkTrieMapGet(b *testing.B) {
+	prefixes := benchmarkPrefixes()
+
+	trieMap := triemap.New[int]()
+	addrs := make([]netip.Addr, 0, len(prefixes))
+	for prefix, value := range prefixes {
+		trieMap.Insert(prefix, value)
+		addrs = append(addrs, prefix.Addr())
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = trieMap.Get(addrs[i%len(addrs)])
+	}
+}
+
+func TestTrieMapGetDoesNotAllocate(t *testing.T) {
+	trieMap := triemap.New[string]()
+	for value, prefixes := range testPrefixes {
+		for _, prefix := range prefixes {
+			trieMap.Insert(prefix, value)
+		}
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		for _, tc := range testCases {
+			_, _ = trieMap.Get(tc.Addr)
+		}
+	})
+	require.Zero(t, allocs)
+}
+
+func TestTrieMapInsertAllocs(t *testing.T) {
+	trieMap := triemap.New[string]()
+	prefix := netip.MustParsePrefix("10.0.0.0/8")
+	trieMap.Insert(prefix, "a")
+
+	// Replacing the value of an existing prefix only allocates the value
+	// itself, its single key is stored inline.
+	allocs := testing.AllocsPerRun(100, func() {
+		trieMap.Insert(prefix, "a")
+	})
+	require.Equal(t, 1.0, allocs)
+}
+
+func BenchmarkTrieMapGetParallel(b *testing.B) {
+	trieMap := triemap.New[int]()
+	for prefix, value := range benchmarkPrefixes() {
+		trieMap.Insert(prefix, value)
+	}
+
+	// A wide set of addresses, so that readers are spread across every lock
+	// shard.
+	rng := rand.New(rand.NewPCG(1, 2))
+	addrs := make([]netip.Addr, 4096)
+	for i := range addrs {
+		addrs[i] = netip.AddrFrom4([4]byte{52, byte(rng.Uint32()), byte(rng.Uint32()), byte(rng.Uint32())})
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := rand.IntN(len(addrs))
+		for pb.Next() {
+			_, _ = trieMap.Get(addrs[i%len(addrs)])
+			i++
+		}
+	})
+}
+
+func TestTrieMapConcurrent(t *testing.T) {
+	trieMap := triemap.New[int](triemap.WithValueIndex())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+
+		// Writers.
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				prefix := netip.PrefixFrom(netip.AddrFrom4([4]byte{10, byte(i), byte(j), 0}), 24)
+				trieMap.Insert(prefix, j%10)
+				if j%3 == 0 {
+					trieMap.Remove(prefix)
+				}
+				trieMap.InsertWeighted(netip.PrefixFrom(netip.AddrFrom16([16]byte{0xfd, byte(i), byte(j)}), 48), j%10, 1)
+			}
+		}()
+
+		// Readers.
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				_, _ = trieMap.Get(netip.AddrFrom4([4]byte{10, byte(i), byte(j), 1}))
+				_, _, _ = trieMap.GetPrefix(netip.AddrFrom16([16]byte{0xfd, byte(i), byte(j), 1}))
+				_ = trieMap.GetAll(netip.AddrFrom4([4]byte{10, byte(i), byte(j), 1}))
+				_ = trieMap.PrefixesFor(j % 10)
+				_ = trieMap.Len()
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.NoError(t, trieMap.Validate())
+}
+
+func BenchmarkTrieMapMixed(b *testing.B) {
+	prefixes := benchmarkPrefixes()
+
+	trieMap := triemap.New[int]()
+	addrs := make([]netip.Addr, 0, len(prefixes))
+	for prefix, value := range prefixes {
+		trieMap.Insert(prefix, value)
+		addrs = append(addrs, prefix.Addr())
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	// One in every hundred operations is a write.
+	b.RunParallel(func(pb *testing.PB) {
+		var i int
+		for pb.Next() {
+			addr := addrs[i%len(addrs)]
+			if i%100 == 0 {
+				trieMap.Insert(netip.PrefixFrom(addr, addr.BitLen()), i%32)
+			} else {
+				_, _ = trieMap.Get(addr)
+			}
+			i++
+		}
+	})
+}
+
+func TestTrieMapSameEntry(t *testing.T) {
+	trieMap := triemap.New[string]()
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	trieMap.Insert(netip.MustParsePrefix("10.1.0.0/16"), "a")
+	trieMap.Insert(netip.MustParsePrefix("::/0"), "b")
+
+	require.True(t, trieMap.SameEntry(netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.2.0.1")))
+	require.True(t, trieMap.SameEntry(netip.MustParseAddr("2001:db8::1"), netip.MustParseAddr("fd00::1")))
+
+	// Same value, but different prefixes.
+	require.False(t, trieMap.SameEntry(netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.1.0.1")))
+
+	// Either address missing.
+	require.False(t, trieMap.SameEntry(netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("192.168.0.1")))
+	require.False(t, trieMap.SameEntry(netip.MustParseAddr("192.168.0.1"), netip.MustParseAddr("192.168.0.1")))
+}
+
+func TestTrieMapGrow(t *testing.T) {
+	trieMap := triemap.New[string](triemap.WithValueIndex())
+
+	// Growing an empty map.
+	trieMap.Grow(100, 10)
+
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	trieMap.Insert(netip.MustParsePrefix("fd00::/8"), "b")
+
+	// Growing a non-empty map should preserve its contents.
+	trieMap.Grow(1000, 100)
+	trieMap.Grow(0, 0)
+
+	value, contains := trieMap.Get(netip.MustParseAddr("10.1.2.3"))
+	require.True(t, contains)
+	require.Equal(t, "a", value)
+
+	require.Equal(t, []netip.Prefix{netip.MustParsePrefix("fd00::/8")}, trieMap.PrefixesFor("b"))
+
+	trieMap.Insert(netip.MustParsePrefix("192.168.0.0/16"), "c")
+	value, contains = trieMap.Get(netip.MustParseAddr("192.168.1.1"))
+	require.True(t, contains)
+	require.Equal(t, "c", value)
+}
+
+func TestTrieMapLengthHistogram(t *testing.T) {
+	trieMap := triemap.New[string]()
+	for value, prefixes := range testPrefixes {
+		for _, prefix := range prefixes {
+			trieMap.Insert(prefix, value)
+		}
+	}
+	trieMap.Insert(netip.MustParsePrefix("0.0.0.0/0"), "default")
+
+	v4, v6 := trieMap.LengthHistogram()
+
+	var expectedV4 [33]int
+	expectedV4[0] = 1
+	expectedV4[16] = 1
+	expectedV4[22] = 1
+	expectedV4[31] = 1
+	expectedV4[32] = 8
+	require.Equal(t, expectedV4, v4)
+
+	var expectedV6 [129]int
+	expectedV6[47] = 1
+	expectedV6[128] = 1
+	require.Equal(t, expectedV6, v6)
+}
+
+func TestTrieMapGetAndRemove(t *testing.T) {
+	trieMap := triemap.New[string]()
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	trieMap.Insert(netip.MustParsePrefix("10.1.0.0/16"), "b")
+	trieMap.Insert(netip.MustParsePrefix("10.1.1.0/24"), "c")
+
+	addr := netip.MustParseAddr("10.1.1.1")
+
+	prefix, value, contains := trieMap.GetAndRemove(addr)
+	require.True(t, contains)
+	require.Equal(t, netip.MustParsePrefix("10.1.1.0/24"), prefix)
+	require.Equal(t, "c", value)
+
+	// The next most specific prefix should now match.
+	prefix, value, contains = trieMap.GetAndRemove(addr)
+	require.True(t, contains)
+	require.Equal(t, netip.MustParsePrefix("10.1.0.0/16"), prefix)
+	require.Equal(t, "b", value)
+
+	prefix, value, contains = trieMap.GetAndRemove(addr)
+	require.True(t, contains)
+	require.Equal(t, netip.MustParsePrefix("10.0.0.0/8"), prefix)
+	require.Equal(t, "a", value)
+
+	_, _, contains = trieMap.GetAndRemove(addr)
+	require.False(t, contains)
+
+	require.True(t, trieMap.Empty())
+}
+
+func TestTrieMapNodePool(t *testing.T) {
+	trieMap := triemap.New[string](triemap.WithNodePool())
+
+	for i := 0; i < 3; i++ {
+		for value, prefixes := range testPrefixes {
+			for _, prefix := range prefixes {
+				trieMap.Insert(prefix, value)
+			}
+		}
+
+		for _, tc := range testCases {
+			value, contains := trieMap.Get(tc.Addr)
+			require.Equal(t, tc.ExpectedValue != "", contains)
+			require.Equal(t, tc.ExpectedValue, value)
+		}
+
+		for value := range testPrefixes {
+			trieMap.RemoveValue(value)
+		}
+		require.True(t, trieMap.Empty())
+
+		for _, tc := range testCases {
+			_, contains := trieMap.Get(tc.Addr)
+			require.False(t, contains)
+		}
+	}
+}
+
+func BenchmarkTrieMapChurn(b *testing.B) {
+	prefixes := make([]netip.Prefix, 1000)
+	for i := range prefixes {
+		prefixes[i] = netip.PrefixFrom(netip.AddrFrom4([4]byte{10, byte(i >> 8), byte(i), 0}), 24+i%9)
+	}
+
+	for _, pooled := range []bool{false, true} {
+		name := "Unpooled"
+		var opts []triemap.Option
+		if pooled {
+			name = "Pooled"
+			opts = append(opts, triemap.WithNodePool())
+		}
+
+		b.Run(name, func(b *testing.B) {
+			trieMap := triemap.New[int](opts...)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				for j, prefix := range prefixes {
+					trieMap.Insert(prefix, j%10)
+				}
+				for j := 0; j < 10; j++ {
+					trieMap.RemoveValue(j)
+				}
+			}
+		})
+	}
+}
+
+func TestTrieMapInsertBatch(t *testing.T) {
+	trieMap := triemap.New[string](triemap.WithValueIndex())
+	trieMap.InsertBatch(
+		triemap.Entry[string]{Prefix: netip.MustParsePrefix("10.0.0.0/8"), Value: "a"},
+		triemap.Entry[string]{Prefix: netip.MustParsePrefix("10.1.0.0/16"), Value: "b"},
+		triemap.Entry[string]{Prefix: netip.MustParsePrefix("fd00::/8"), Value: "a"},
+		triemap.Entry[string]{Prefix: netip.MustParsePrefix("10.1.0.0/16"), Value: "c"},
+	)
+
+	require.Equal(t, 3, trieMap.Len())
+	require.NoError(t, trieMap.Validate())
+
+	// Later entries replace earlier ones.
+	value, ok := trieMap.Get(netip.MustParseAddr("10.1.2.3"))
+	require.True(t, ok)
+	require.Equal(t, "c", value)
+
+	// Repeated values share a key.
+	require.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("fd00::/8"),
+	}, trieMap.PrefixesFor("a"))
+	require.Empty(t, trieMap.PrefixesFor("b"))
+}
+
+func BenchmarkTrieMapInsertBatch(b *testing.B) {
+	entries := make([]triemap.Entry[int], 50000)
+	for i := range entries {
+		entries[i] = triemap.Entry[int]{
+			Prefix: netip.PrefixFrom(netip.AddrFrom4([4]byte{10, byte(i >> 16), byte(i >> 8), byte(i)}), 32),
+			Value:  i % 100,
+		}
+	}
+
+	b.Run("Insert", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			trieMap := triemap.New[int]()
+			for _, e := range entries {
+				trieMap.Insert(e.Prefix, e.Value)
+			}
+		}
+	})
+
+	b.Run("InsertBatch", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			trieMap := triemap.New[int]()
+			trieMap.InsertBatch(entries...)
+		}
+	})
+}
+
+func TestTrieMapInsertBoth(t *testing.T) {
+	trieMap := triemap.New[string](triemap.WithValueIndex())
+	require.NoError(t, trieMap.InsertBoth(netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("fd00::/8"), "private"))
+
+	require.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("fd00::/8"),
+	}, trieMap.PrefixesFor("private"))
+
+	// The prefixes must be of the expected families.
+	require.Error(t, trieMap.InsertBoth(netip.MustParsePrefix("fd00::/8"), netip.MustParsePrefix("fd00::/8"), "a"))
+	require.Error(t, trieMap.InsertBoth(netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("10.0.0.0/8"), "a"))
+	require.Error(t, trieMap.InsertBoth(netip.MustParsePrefix("::ffff:0.0.0.0/80"), netip.MustParsePrefix("fd00::/8"), "a"))
+	require.Error(t, trieMap.InsertBoth(netip.Prefix{}, netip.MustParsePrefix("fd00::/8"), "a"))
+	require.Empty(t, trieMap.PrefixesFor("a"))
+
+	// IPv4-mapped prefixes are inserted as the equivalent IPv4 prefix.
+	require.NoError(t, trieMap.InsertBoth(netip.MustParsePrefix("::ffff:172.16.0.0/108"), netip.MustParsePrefix("fc00::/8"), "mapped"))
+	require.NoError(t, trieMap.Validate())
+	require.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("172.16.0.0/12"),
+		netip.MustParsePrefix("fc00::/8"),
+	}, trieMap.PrefixesFor("mapped"))
+
+	value, ok := trieMap.Get(netip.MustParseAddr("172.16.1.2"))
+	require.True(t, ok)
+	require.Equal(t, "mapped", value)
+
+	trieMap.InsertDefault("default")
+	value, ok = trieMap.Get(netip.MustParseAddr("192.168.1.1"))
+	require.True(t, ok)
+	require.Equal(t, "default", value)
+	value, ok = trieMap.Get(netip.MustParseAddr("2001:db8::1"))
+	require.True(t, ok)
+	require.Equal(t, "default", value)
+
+	require.Equal(t, 6, trieMap.Len())
+	require.NoError(t, trieMap.Validate())
+}
+
+func TestTrieMapUpdate(t *testing.T) {
+	trieMap := triemap.New[int](triemap.WithValueIndex())
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), 8)
+	trieMap.Insert(netip.MustParsePrefix("10.1.0.0/16"), 4)
+	trieMap.Insert(netip.MustParsePrefix("10.1.1.0/24"), 2)
+	trieMap.Insert(netip.MustParsePrefix("fd00::/64"), 1)
+
+	// Halve every score, dropping entries that decay to zero.
+	trieMap.Update(func(prefix netip.Prefix, value int) (int, bool) {
+		return value / 2, value/2 > 0
+	})
+
+	value, ok := trieMap.Get(netip.MustParseAddr("10.1.1.1"))
+	require.True(t, ok)
+	require.Equal(t, 1, value)
+
+	value, ok = trieMap.Get(netip.MustParseAddr("10.2.0.1"))
+	require.True(t, ok)
+	require.Equal(t, 4, value)
+
+	_, ok = trieMap.Get(netip.MustParseAddr("fd00::1"))
+	require.False(t, ok)
+
+	// Values that are no longer referenced are dropped, and values shared
+	// with other prefixes are reused.
+	require.Empty(t, trieMap.PrefixesFor(8))
+	require.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.1.1.0/24")}, trieMap.PrefixesFor(1))
+
+	// Collapse every value into one that already exists.
+	trieMap.Update(func(prefix netip.Prefix, value int) (int, bool) {
+		return 2, true
+	})
+
+	require.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("10.1.0.0/16"),
+		netip.MustParsePrefix("10.1.1.0/24"),
+	}, trieMap.PrefixesFor(2))
+	require.Empty(t, trieMap.PrefixesFor(1))
+	require.Empty(t, trieMap.PrefixesFor(4))
+
+	trieMap.Update(func(prefix netip.Prefix, value int) (int, bool) {
+		return 0, false
+	})
+	require.True(t, trieMap.Empty())
+}
+
+func TestTrieMapReplace(t *testing.T) {
+	trieMap := triemap.New[string](triemap.WithValueIndex())
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	trieMap.Insert(netip.MustParsePrefix("192.168.0.0/16"), "b")
+
+	// Replacing with an already present value reuses it.
+	require.True(t, trieMap.Replace(netip.MustParsePrefix("10.0.0.0/8"), "b"))
+	require.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.168.0.0/16"),
+	}, trieMap.PrefixesFor("b"))
+
+	// The old value is no longer referenced, so it is dropped.
+	require.Empty(t, trieMap.PrefixesFor("a"))
+	require.NoError(t, trieMap.Validate())
+
+	// Replacing with a new value.
+	require.True(t, trieMap.Replace(netip.MustParsePrefix("192.168.0.0/16"), "c"))
+	value, ok := trieMap.Get(netip.MustParseAddr("192.168.1.1"))
+	require.True(t, ok)
+	require.Equal(t, "c", value)
+	require.NoError(t, trieMap.Validate())
+
+	// Only exact prefixes are replaced.
+	require.False(t, trieMap.Replace(netip.MustParsePrefix("10.1.0.0/16"), "d"))
+	require.False(t, trieMap.Replace(netip.MustParsePrefix("fd00::/8"), "d"))
+	require.Empty(t, trieMap.PrefixesFor("d"))
+
+	require.Equal(t, 2, trieMap.Len())
+	require.NoError(t, trieMap.Validate())
+}
+
+func TestTrieMapOnlyDefaultRoute(t *testing.T) {
+	trieMap := triemap.New[string]()
+	trieMap.Insert(netip.MustParsePrefix("0.0.0.0/0"), "default")
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	trieMap.Insert(netip.MustParsePrefix("192.168.1.0/24"), "b")
+
+	// Covered by a more specific prefix.
+	require.False(t, trieMap.OnlyDefaultRoute(netip.MustParsePrefix("10.1.0.0/16")))
+	// Partially covered by a more specific prefix.
+	require.False(t, trieMap.OnlyDefaultRoute(netip.MustParsePrefix("192.168.0.0/16")))
+	require.False(t, trieMap.OnlyDefaultRoute(netip.MustParsePrefix("0.0.0.0/0")))
+
+	require.True(t, trieMap.OnlyDefaultRoute(netip.MustParsePrefix("172.16.0.0/12")))
+	require.True(t, trieMap.OnlyDefaultRoute(netip.MustParsePrefix("192.168.2.0/24")))
+	require.True(t, trieMap.OnlyDefaultRoute(netip.MustParsePrefix("fd00::/8")))
+}
+
+func TestTrieMapOverlaps(t *testing.T) {
+	trieMap := triemap.New[string]()
+	require.False(t, trieMap.Overlaps(netip.MustParsePrefix("10.0.0.0/8")))
+
+	trieMap.Insert(netip.MustParsePrefix("10.1.2.0/24"), "a")
+	trieMap.Insert(netip.MustParsePrefix("2001:db8::/32"), "b")
+
+	// The candidate contains a stored prefix.
+	require.True(t, trieMap.Overlaps(netip.MustParsePrefix("10.1.0.0/16")))
+	require.True(t, trieMap.Overlaps(netip.MustParsePrefix("2000::/3")))
+
+	// The candidate is contained by a stored prefix.
+	require.True(t, trieMap.Overlaps(netip.MustParsePrefix("10.1.2.128/25")))
+	require.True(t, trieMap.Overlaps(netip.MustParsePrefix("2001:db8:1::/48")))
+
+	// The candidate equals a stored prefix.
+	require.True(t, trieMap.Overlaps(netip.MustParsePrefix("10.1.2.0/24")))
+	require.True(t, trieMap.Overlaps(netip.MustParsePrefix("2001:db8::/32")))
+
+	// Disjoint prefixes do not overlap.
+	require.False(t, trieMap.Overlaps(netip.MustParsePrefix("10.1.3.0/24")))
+	require.False(t, trieMap.Overlaps(netip.MustParsePrefix("2001:db9::/32")))
+
+	// Nor do prefixes of different families.
+	trieMap.Remove(netip.MustParsePrefix("2001:db8::/32"))
+	require.False(t, trieMap.Overlaps(netip.MustParsePrefix("::/0")))
+	require.True(t, trieMap.Overlaps(netip.MustParsePrefix("0.0.0.0/0")))
+
+	require.False(t, trieMap.Overlaps(netip.Prefix{}))
+}
+
+func TestTrieMapPreserveInsertedPrefix(t *testing.T) {
+	inserted := netip.MustParsePrefix("10.1.2.3/8")
+	masked := netip.MustParsePrefix("10.0.0.0/8")
+
+	t.Run("Default", func(t *testing.T) {
+		trieMap := triemap.New[string]()
+		trieMap.Insert(inserted, "a")
+
+		require.Equal(t, []netip.Prefix{masked}, trieMap.PrefixesFor("a"))
+
+		value, ok := trieMap.Get(netip.MustParseAddr("10.255.0.1"))
+		require.True(t, ok)
+		require.Equal(t, "a", value)
+	})
+
+	t.Run("Preserved", func(t *testing.T) {
+		trieMap := triemap.New[string](triemap.WithPreserveInsertedPrefix())
+		trieMap.Insert(inserted, "a")
+
+		require.Equal(t, []netip.Prefix{inserted}, trieMap.PrefixesFor("a"))
+
+		value, ok := trieMap.Get(netip.MustParseAddr("10.255.0.1"))
+		require.True(t, ok)
+		require.Equal(t, "a", value)
+	})
+
+	for _, remove := range []netip.Prefix{inserted, masked} {
+		t.Run("Remove "+remove.String(), func(t *testing.T) {
+			trieMap := triemap.New[string](triemap.WithPreserveInsertedPrefix(), triemap.WithValueIndex())
+			trieMap.Insert(inserted, "a")
+
+			require.True(t, trieMap.Remove(remove))
+			require.True(t, trieMap.Empty())
+			require.Empty(t, trieMap.PrefixesFor("a"))
+		})
+	}
+}
+
+func TestTrieMapGetWhere(t *testing.T) {
+	healthy := map[string]bool{"us-east": true, "eu-west": false, "eu-central": true}
+
+	trieMap := triemap.New[string]()
+	trieMap.Insert(netip.MustParsePrefix("0.0.0.0/0"), "us-east")
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "eu-central")
+	trieMap.Insert(netip.MustParsePrefix("10.1.0.0/16"), "eu-west")
+
+	isHealthy := func(region string) bool { return healthy[region] }
+
+	// The most specific prefix is unhealthy, so fail over to its parent.
+	prefix, value, ok := trieMap.GetWhere(netip.MustParseAddr("10.1.2.3"), isHealthy)
+	require.True(t, ok)
+	require.Equal(t, netip.MustParsePrefix("10.0.0.0/8"), prefix)
+	require.Equal(t, "eu-central", value)
+
+	prefix, value, ok = trieMap.GetWhere(netip.MustParseAddr("192.168.1.1"), isHealthy)
+	require.True(t, ok)
+	require.Equal(t, netip.MustParsePrefix("0.0.0.0/0"), prefix)
+	require.Equal(t, "us-east", value)
+
+	_, _, ok = trieMap.GetWhere(netip.MustParseAddr("10.1.2.3"), func(string) bool { return false })
+	require.False(t, ok)
+}
+
+func TestTrieMapGetUpToLen(t *testing.T) {
+	trieMap := triemap.New[string]()
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	trieMap.Insert(netip.MustParsePrefix("10.1.0.0/16"), "b")
+	trieMap.Insert(netip.MustParsePrefix("10.1.2.3/32"), "c")
+	trieMap.Insert(netip.MustParsePrefix("2001:db8::/32"), "d")
+	trieMap.Insert(netip.MustParsePrefix("2001:db8::1/128"), "e")
+
+	addr := netip.MustParseAddr("10.1.2.3")
+
+	prefix, value, ok := trieMap.GetUpToLen(addr, 32)
+	require.True(t, ok)
+	require.Equal(t, netip.MustParsePrefix("10.1.2.3/32"), prefix)
+	require.Equal(t, "c", value)
+
+	prefix, value, ok = trieMap.GetUpToLen(addr, 24)
+	require.True(t, ok)
+	require.Equal(t, netip.MustParsePrefix("10.1.0.0/16"), prefix)
+	require.Equal(t, "b", value)
+
+	prefix, value, ok = trieMap.GetUpToLen(addr, 8)
+	require.True(t, ok)
+	require.Equal(t, netip.MustParsePrefix("10.0.0.0/8"), prefix)
+	require.Equal(t, "a", value)
+
+	_, _, ok = trieMap.GetUpToLen(addr, 7)
+	require.False(t, ok)
+
+	prefix, value, ok = trieMap.GetUpToLen(netip.MustParseAddr("2001:db8::1"), 64)
+	require.True(t, ok)
+	require.Equal(t, netip.MustParsePrefix("2001:db8::/32"), prefix)
+	require.Equal(t, "d", value)
+
+	_, _, ok = trieMap.GetUpToLen(netip.MustParseAddr("192.168.1.1"), 32)
+	require.False(t, ok)
+}
+
+func TestTrieMapWalk(t *testing.T) {
+	trieMap := triemap.New[string]()
+	trieMap.Insert(netip.MustParsePrefix("fd00::/8"), "e")
+	trieMap.Insert(netip.MustParsePrefix("192.168.1.0/24"), "d")
+	trieMap.Insert(netip.MustParsePrefix("10.1.0.0/16"), "c")
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "b")
+	trieMap.Insert(netip.MustParsePrefix("0.0.0.0/0"), "a")
+
+	var entries []triemap.Entry[string]
+	trieMap.Walk(func(prefix netip.Prefix, value string) bool {
+		entries = append(entries, triemap.Entry[string]{Prefix: prefix, Value: value})
+		return true
+	})
+
+	require.Equal(t, []triemap.Entry[string]{
+		{Prefix: netip.MustParsePrefix("0.0.0.0/0"), Value: "a"},
+		{Prefix: netip.MustParsePrefix("10.0.0.0/8"), Value: "b"},
+		{Prefix: netip.MustParsePrefix("10.1.0.0/16"), Value: "c"},
+		{Prefix: netip.MustParsePrefix("192.168.1.0/24"), Value: "d"},
+		{Prefix: netip.MustParsePrefix("fd00::/8"), Value: "e"},
+	}, entries)
+
+	// Stop early.
+	var count int
+	trieMap.Walk(func(netip.Prefix, string) bool {
+		count++
+		return count < 2
+	})
+	require.Equal(t, 2, count)
+}
+
+func TestTrieMapAll(t *testing.T) {
+	trieMap := triemap.New[string]()
+	trieMap.Insert(netip.MustParsePrefix("fd00::/8"), "c")
+	trieMap.Insert(netip.MustParsePrefix("10.1.0.0/16"), "b")
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+
+	var entries []triemap.Entry[string]
+	for prefix, value := range trieMap.All() {
+		entries = append(entries, triemap.Entry[string]{Prefix: prefix, Value: value})
+	}
+	require.Equal(t, []triemap.Entry[string]{
+		{Prefix: netip.MustParsePrefix("10.0.0.0/8"), Value: "a"},
+		{Prefix: netip.MustParsePrefix("10.1.0.0/16"), Value: "b"},
+		{Prefix: netip.MustParsePrefix("fd00::/8"), Value: "c"},
+	}, entries)
+
+	// Breaking early releases the read lock.
+	for range trieMap.All() {
+		break
+	}
+	trieMap.Insert(netip.MustParsePrefix("192.168.0.0/16"), "d")
+	require.Equal(t, 4, trieMap.Len())
+}
+
+func TestTrieMapLen(t *testing.T) {
+	trieMap := triemap.New[string]()
+	require.Zero(t, trieMap.Len())
+
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	trieMap.Insert(netip.MustParsePrefix("10.1.0.0/16"), "b")
+	trieMap.Insert(netip.MustParsePrefix("fd00::/8"), "c")
+	require.Equal(t, 3, trieMap.Len())
+
+	// Overwriting a prefix doesn't double count.
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "d")
+	trieMap.Insert(netip.MustParsePrefix("10.1.2.3/8"), "e")
+	require.Equal(t, 3, trieMap.Len())
+
+	// Nor do weighted values.
+	trieMap.InsertWeighted(netip.MustParsePrefix("fd00::/8"), "f", 1)
+	require.Equal(t, 3, trieMap.Len())
+
+	trieMap.RemoveValue("c")
+	require.Equal(t, 3, trieMap.Len())
+
+	trieMap.RemoveValue("f")
+	require.Equal(t, 2, trieMap.Len())
+
+	require.True(t, trieMap.Remove(netip.MustParsePrefix("10.1.0.0/16")))
+	require.False(t, trieMap.Remove(netip.MustParsePrefix("10.1.0.0/16")))
+	require.Equal(t, 1, trieMap.Len())
+
+	trieMap.Update(func(netip.Prefix, string) (string, bool) { return "", false })
+	require.Zero(t, trieMap.Len())
+}
+
+func TestTrieMapValueLen(t *testing.T) {
+	trieMap := triemap.New[string]()
+	require.Zero(t, trieMap.ValueLen())
+
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	trieMap.Insert(netip.MustParsePrefix("10.1.0.0/16"), "a")
+	trieMap.Insert(netip.MustParsePrefix("fd00::/8"), "b")
+	trieMap.Insert(netip.MustParsePrefix("192.168.0.0/16"), "c")
+	require.Equal(t, 3, trieMap.ValueLen())
+
+	// Overwriting the last prefix for a value drops it.
+	trieMap.Insert(netip.MustParsePrefix("192.168.0.0/16"), "a")
+	require.Equal(t, 2, trieMap.ValueLen())
+
+	// As does removing the last prefix for a value.
+	require.True(t, trieMap.Remove(netip.MustParsePrefix("fd00::/8")))
+	require.Equal(t, 1, trieMap.ValueLen())
+
+	// But not while other prefixes still reference it.
+	require.True(t, trieMap.Remove(netip.MustParsePrefix("10.0.0.0/8")))
+	require.Equal(t, 1, trieMap.ValueLen())
+
+	trieMap.RemoveValue("a")
+	require.Zero(t, trieMap.ValueLen())
+	require.Zero(t, trieMap.Len())
+}
+
+func TestTrieMapGetPrefix(t *testing.T) {
+	trieMap := triemap.New[string]()
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	trieMap.Insert(netip.MustParsePrefix("10.1.0.0/16"), "b")
+
+	prefix, value, ok := trieMap.GetPrefix(netip.MustParseAddr("10.1.2.3"))
+	require.True(t, ok)
+	require.Equal(t, netip.MustParsePrefix("10.1.0.0/16"), prefix)
+	require.Equal(t, "b", value)
+
+	prefix, value, ok = trieMap.GetPrefix(netip.MustParseAddr("10.2.0.1"))
+	require.True(t, ok)
+	require.Equal(t, netip.MustParsePrefix("10.0.0.0/8"), prefix)
+	require.Equal(t, "a", value)
+
+	prefix, _, ok = trieMap.GetPrefix(netip.MustParseAddr("192.168.1.1"))
+	require.False(t, ok)
+	require.False(t, prefix.IsValid())
+}
+
+func TestTrieMapContains(t *testing.T) {
+	trieMap := triemap.New[string]()
+	require.False(t, trieMap.Contains(netip.MustParseAddr("10.0.0.1")))
+
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	trieMap.Insert(netip.MustParsePrefix("10.1.2.3/32"), "b")
+	trieMap.Insert(netip.MustParsePrefix("2001:db8::/32"), "c")
+
+	require.True(t, trieMap.Contains(netip.MustParseAddr("10.1.2.3")))
+	require.True(t, trieMap.Contains(netip.MustParseAddr("10.200.0.1")))
+	// Like netip.Prefix.Contains, IPv4-mapped addresses are IPv6 addresses.
+	require.False(t, trieMap.Contains(netip.MustParseAddr("::ffff:10.0.0.1")))
+	require.True(t, trieMap.Contains(netip.MustParseAddr("2001:db8::1")))
+	require.False(t, trieMap.Contains(netip.MustParseAddr("192.168.1.1")))
+	require.False(t, trieMap.Contains(netip.MustParseAddr("fd00::1")))
+
+	// Disabled prefixes are skipped.
+	trieMap.SetEnabled("a", false)
+	require.True(t, trieMap.Contains(netip.MustParseAddr("10.1.2.3")))
+	require.False(t, trieMap.Contains(netip.MustParseAddr("10.200.0.1")))
+
+	// A default route contains every address of its family.
+	trieMap.Insert(netip.MustParsePrefix("0.0.0.0/0"), "d")
+	require.True(t, trieMap.Contains(netip.MustParseAddr("192.168.1.1")))
+	require.False(t, trieMap.Contains(netip.MustParseAddr("fd00::1")))
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = trieMap.Contains(netip.MustParseAddr("10.1.2.3"))
+	})
+	require.Zero(t, allocs)
+}
+
+func TestTrieMapRemoveIf(t *testing.T) {
+	trieMap := triemap.New[string]()
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "stale")
+	trieMap.Insert(netip.MustParsePrefix("10.1.0.0/16"), "fresh")
+	trieMap.Insert(netip.MustParsePrefix("10.1.2.0/24"), "stale")
+	trieMap.Insert(netip.MustParsePrefix("192.168.1.0/24"), "fresh")
+	trieMap.Insert(netip.MustParsePrefix("2001:db8::/32"), "stale")
+	trieMap.Insert(netip.MustParsePrefix("2001:db8:1::/48"), "fresh")
+
+	// Remove by value.
+	removed := trieMap.RemoveIf(func(_ netip.Prefix, value string) bool {
+		return value == "stale"
+	})
+	require.Equal(t, 3, removed)
+	require.Equal(t, 3, trieMap.Len())
+	require.Equal(t, 1, trieMap.ValueLen())
+	require.NoError(t, trieMap.Validate())
+
+	_, ok := trieMap.Get(netip.MustParseAddr("10.200.0.1"))
+	require.False(t, ok)
+
+	value, ok := trieMap.Get(netip.MustParseAddr("10.1.2.3"))
+	require.True(t, ok)
+	require.Equal(t, "fresh", value)
+
+	// Remove by prefix length.
+	removed = trieMap.RemoveIf(func(prefix netip.Prefix, _ string) bool {
+		return prefix.Bits() == 24
+	})
+	require.Equal(t, 1, removed)
+	require.Equal(t, 2, trieMap.Len())
+	require.NoError(t, trieMap.Validate())
+
+	// Nothing matches.
+	removed = trieMap.RemoveIf(func(netip.Prefix, string) bool { return false })
+	require.Zero(t, removed)
+	require.Equal(t, 2, trieMap.Len())
+
+	// Remove everything.
+	removed = trieMap.RemoveIf(func(netip.Prefix, string) bool { return true })
+	require.Equal(t, 2, removed)
+	require.True(t, trieMap.Empty())
+	require.Zero(t, trieMap.ValueLen())
+	require.NoError(t, trieMap.Validate())
+}
+
+func TestTrieMapInvalid(t *testing.T) {
+	trieMap := triemap.New[string]()
+	trieMap.Insert(netip.MustParsePrefix("::/0"), "default")
+
+	// Invalid addresses never match.
+	_, ok := trieMap.Get(netip.Addr{})
+	require.False(t, ok)
+	require.False(t, trieMap.Contains(netip.Addr{}))
+
+	// Invalid prefixes are ignored.
+	trieMap.Insert(netip.Prefix{}, "invalid")
+	trieMap.InsertBatch(triemap.Entry[string]{Value: "invalid"})
+	trieMap.InsertWeighted(netip.Prefix{}, "invalid", 1)
+	trieMap.InsertTTL(netip.Prefix{}, "invalid", time.Minute)
+	require.Equal(t, 1, trieMap.Len())
+	require.Equal(t, 1, trieMap.ValueLen())
+	require.False(t, trieMap.Remove(netip.Prefix{}))
+	require.NoError(t, trieMap.Validate())
+
+	value, ok := trieMap.Get(netip.MustParseAddr("2001:db8::1"))
+	require.True(t, ok)
+	require.Equal(t, "default", value)
+}
+
+func TestTrieMapIPv4Mapped(t *testing.T) {
+	trieMap := triemap.New[string]()
+	trieMap.Insert(netip.MustParsePrefix("0.0.0.0/0"), "ipv4")
+
+	// Like netip.Prefix.Contains, IPv4-mapped addresses are IPv6 addresses.
+	_, ok := trieMap.Get(netip.MustParseAddr("::ffff:1.2.3.4"))
+	require.False(t, ok)
+
+	trieMap.Insert(netip.MustParsePrefix("::ffff:10.0.0.0/104"), "mapped")
+	require.Equal(t, 2, trieMap.Len())
+	require.NoError(t, trieMap.Validate())
+
+	value, ok := trieMap.Get(netip.MustParseAddr("::ffff:10.1.2.3"))
+	require.True(t, ok)
+	require.Equal(t, "mapped", value)
+
+	value, ok = trieMap.Get(netip.MustParseAddr("10.1.2.3"))
+	require.True(t, ok)
+	require.Equal(t, "ipv4", value)
+
+	// Mapped prefixes can be removed again.
+	require.True(t, trieMap.Remove(netip.MustParsePrefix("::ffff:10.0.0.0/104")))
+	require.Equal(t, 1, trieMap.Len())
+	require.NoError(t, trieMap.Validate())
+
+	_, ok = trieMap.Get(netip.MustParseAddr("::ffff:10.1.2.3"))
+	require.False(t, ok)
+}
+
+func TestTrieMapGetAddrPort(t *testing.T) {
+	trieMap := triemap.New[string]()
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	trieMap.Insert(netip.MustParsePrefix("2001:db8::/32"), "b")
+
+	value, ok := trieMap.GetAddrPort(netip.MustParseAddrPort("10.1.2.3:443"))
+	require.True(t, ok)
+	require.Equal(t, "a", value)
+
+	prefix, value, ok := trieMap.GetAddrPortPrefix(netip.MustParseAddrPort("[2001:db8::1]:53"))
+	require.True(t, ok)
+	require.Equal(t, netip.MustParsePrefix("2001:db8::/32"), prefix)
+	require.Equal(t, "b", value)
+
+	_, ok = trieMap.GetAddrPort(netip.MustParseAddrPort("192.168.1.1:80"))
+	require.False(t, ok)
+
+	_, _, ok = trieMap.GetAddrPortPrefix(netip.AddrPort{})
+	require.False(t, ok)
+}
+
+func TestTrieMapSetEnabled(t *testing.T) {
+	trieMap := triemap.New[string]()
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	trieMap.Insert(netip.MustParsePrefix("10.1.0.0/16"), "b")
+	trieMap.Insert(netip.MustParsePrefix("10.2.0.0/16"), "b")
+	trieMap.InsertWeighted(netip.MustParsePrefix("fd00::/8"), "b", 1)
+	trieMap.InsertWeighted(netip.MustParsePrefix("fd00::/8"), "c", 1)
+
+	trieMap.SetEnabled("b", false)
+
+	// Disabled prefixes are skipped in favor of the next best match.
+	for _, addr := range []string{"10.1.0.1", "10.2.0.1"} {
+		prefix, value, ok := trieMap.GetPrefix(netip.MustParseAddr(addr))
+		require.True(t, ok)
+		require.Equal(t, netip.MustParsePrefix("10.0.0.0/8"), prefix)
+		require.Equal(t, "a", value)
+	}
+
+	// Prefixes with other enabled values are still matched.
+	for i := 0; i < 10; i++ {
+		value, ok := trieMap.GetWeighted(netip.MustParseAddr("fd00::1"), nil)
+		require.True(t, ok)
+		require.Equal(t, "c", value)
+	}
+	value, ok := trieMap.Get(netip.MustParseAddr("fd00::1"))
+	require.True(t, ok)
+	require.Equal(t, "c", value)
+
+	// Disabled prefixes still occupy the trie.
+	require.Equal(t, 4, trieMap.Len())
+
+	trieMap.SetEnabled("b", true)
+
+	value, ok = trieMap.Get(netip.MustParseAddr("10.1.0.1"))
+	require.True(t, ok)
+	require.Equal(t, "b", value)
+
+	// The disabled state is forgotten once the value is removed.
+	trieMap.SetEnabled("b", false)
+	trieMap.RemoveValue("b")
+	trieMap.Insert(netip.MustParsePrefix("10.1.0.0/16"), "b")
+
+	value, ok = trieMap.Get(netip.MustParseAddr("10.1.0.1"))
+	require.True(t, ok)
+	require.Equal(t, "b", value)
+}
+
+func TestTrieMapLookupPrefix(t *testing.T) {
+	trieMap := triemap.New[string]()
+	trieMap.Insert(netip.MustParsePrefix("192.168.0.0/16"), "a")
+	trieMap.Insert(netip.MustParsePrefix("192.168.1.0/26"), "b")
+
+	// Get matches the covering /16.
+	value, ok := trieMap.Get(netip.MustParseAddr("192.168.1.0"))
+	require.True(t, ok)
+	require.Equal(t, "b", value)
+
+	value, ok = trieMap.Get(netip.MustParseAddr("192.168.1.128"))
+	require.True(t, ok)
+	require.Equal(t, "a", value)
+
+	// But LookupPrefix only matches the exact prefix.
+	_, ok = trieMap.LookupPrefix(netip.MustParsePrefix("192.168.1.0/24"))
+	require.False(t, ok)
+
+	_, ok = trieMap.LookupPrefix(netip.MustParsePrefix("192.168.1.0/27"))
+	require.False(t, ok)
+
+	value, ok = trieMap.LookupPrefix(netip.MustParsePrefix("192.168.1.0/26"))
+	require.True(t, ok)
+	require.Equal(t, "b", value)
+
+	value, ok = trieMap.LookupPrefix(netip.MustParsePrefix("192.168.0.0/16"))
+	require.True(t, ok)
+	require.Equal(t, "a", value)
+
+	_, ok = trieMap.LookupPrefix(netip.MustParsePrefix("fd00::/16"))
+	require.False(t, ok)
+}
+
+func TestTrieMapClear(t *testing.T) {
+	for _, opts := range [][]triemap.Option{nil, {triemap.WithValueIndex(), triemap.WithNodePool()}} {
+		trieMap := triemap.New[string](opts...)
+		trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+		trieMap.Insert(netip.MustParsePrefix("10.1.0.0/16"), "b")
+		trieMap.Insert(netip.MustParsePrefix("fd00::/8"), "c")
+		trieMap.SetEnabled("a", false)
+
+		trieMap.Clear()
+
+		require.True(t, trieMap.Empty())
+		require.Zero(t, trieMap.Len())
+		require.Empty(t, trieMap.PrefixesFor("a"))
+		_, ok := trieMap.Get(netip.MustParseAddr("10.1.0.1"))
+		require.False(t, ok)
+
+		// Subsequent inserts work.
+		trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+		trieMap.Insert(netip.MustParsePrefix("192.168.0.0/16"), "d")
+
+		value, ok := trieMap.Get(netip.MustParseAddr("10.1.0.1"))
+		require.True(t, ok)
+		require.Equal(t, "a", value)
+
+		require.Equal(t, 2, trieMap.Len())
+		require.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}, trieMap.PrefixesFor("a"))
+	}
+}
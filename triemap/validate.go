@@ -0,0 +1,142 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+
+	"github.com/noisysockets/util/internal/addrbits"
+)
+
+// Validate checks the internal consistency of the TrieMap, returning an error
+// describing the first inconsistency found. It is intended for use in tests
+// and when debugging, and is linear in the size of the TrieMap.
+func (t *TrieMap[V]) Validate() error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	refs := make(map[int]int)
+	prefixes := make(map[int][]netip.Prefix)
+	var count int
+
+	for _, root := range []*trieNode{t.trieMap.ipv4Root, t.trieMap.ipv6Root} {
+		if root == nil {
+			continue
+		}
+		if root.bits != 0 {
+			return fmt.Errorf("root node has a length of %d", root.bits)
+		}
+
+		totalBits := 128
+		if root == t.trieMap.ipv4Root {
+			totalBits = 32
+		}
+
+		stack := []*trieNode{root}
+		for len(stack) > 0 {
+			curr := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			if curr.addr != maskBits(curr.addr, totalBits, curr.bits) {
+				return fmt.Errorf("node /%d has host bits set", curr.bits)
+			}
+			// Only the root node may be empty, every other node must hold a
+			// value or branch.
+			if curr != root && curr.value == nil && (curr.child0 == nil || curr.child1 == nil) {
+				return fmt.Errorf("node /%d is redundant", curr.bits)
+			}
+
+			if value := curr.value; value != nil {
+				count++
+
+				if addr, _ := addrbits.FromAddr(value.prefix.Masked().Addr()); value.prefix.Bits() != curr.bits || addr != curr.addr {
+					return fmt.Errorf("prefix %s stored at the wrong node", value.prefix)
+				}
+				if t.trieMap.find(value.prefix) != value {
+					return fmt.Errorf("prefix %s is not reachable", value.prefix)
+				}
+				if len(value.keys) == 0 {
+					return fmt.Errorf("prefix %s has no values", value.prefix)
+				}
+
+				for _, k := range value.keys {
+					if _, ok := t.keyToValue[k.key]; !ok {
+						return fmt.Errorf("prefix %s references unknown key %d", value.prefix, k.key)
+					}
+					refs[k.key]++
+					prefixes[k.key] = append(prefixes[k.key], value.prefix)
+				}
+			}
+
+			for _, bit := range []bool{false, true} {
+				child := curr.child(bit)
+				if child == nil {
+					continue
+				}
+				if child.bits <= curr.bits || addrbits.CommonLen(child.addr, curr.addr, totalBits) < curr.bits || bitAt(child.addr, totalBits, curr.bits) != bit {
+					return fmt.Errorf("node /%d is not a valid child of node /%d", child.bits, curr.bits)
+				}
+				stack = append(stack, child)
+			}
+		}
+	}
+
+	if count != t.trieMap.len {
+		return fmt.Errorf("length is %d but there are %d prefixes", t.trieMap.len, count)
+	}
+
+	if len(refs) != len(t.trieMap.keyRefs) {
+		return fmt.Errorf("%d keys have references but %d are counted", len(refs), len(t.trieMap.keyRefs))
+	}
+	for key, n := range refs {
+		if t.trieMap.keyRefs[key] != n {
+			return fmt.Errorf("key %d has %d references but %d are counted", key, n, t.trieMap.keyRefs[key])
+		}
+	}
+
+	if len(t.keyToValue) != len(t.valueToKey) {
+		return fmt.Errorf("bimap has %d keys but %d values", len(t.keyToValue), len(t.valueToKey))
+	}
+	for key, value := range t.keyToValue {
+		if k, ok := t.valueToKey[value]; !ok || k != key {
+			return fmt.Errorf("bimap is not bijective for key %d", key)
+		}
+		if refs[key] == 0 {
+			return fmt.Errorf("key %d is not referenced by any prefix", key)
+		}
+		if key >= t.nextKey {
+			return fmt.Errorf("key %d has not been allocated", key)
+		}
+	}
+
+	for key := range t.trieMap.disabled {
+		if _, ok := t.keyToValue[key]; !ok {
+			return fmt.Errorf("unknown key %d is disabled", key)
+		}
+	}
+
+	if t.trieMap.keyPrefixes != nil {
+		if len(t.trieMap.keyPrefixes) != len(prefixes) {
+			return fmt.Errorf("value index has %d keys but %d are referenced", len(t.trieMap.keyPrefixes), len(prefixes))
+		}
+		for key, expected := range prefixes {
+			indexed := slices.Clone(t.trieMap.keyPrefixes[key])
+			slices.SortFunc(indexed, comparePrefixes)
+			slices.SortFunc(expected, comparePrefixes)
+			if !slices.Equal(indexed, expected) {
+				return fmt.Errorf("value index for key %d is %v, expected %v", key, indexed, expected)
+			}
+		}
+	}
+
+	return nil
+}
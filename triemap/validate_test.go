@@ -0,0 +1,54 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap_test
+
+import (
+	"math/rand/v2"
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/triemap"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrieMapValidate(t *testing.T) {
+	trieMap := triemap.New[int](triemap.WithValueIndex(), triemap.WithNodePool())
+	require.NoError(t, trieMap.Validate())
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	randomPrefix := func() netip.Prefix {
+		if rng.IntN(2) == 0 {
+			return netip.PrefixFrom(netip.AddrFrom4([4]byte{10, byte(rng.IntN(4)), byte(rng.IntN(4)), 0}), 8+rng.IntN(17))
+		}
+		return netip.PrefixFrom(netip.AddrFrom16([16]byte{0xfd, 0, byte(rng.IntN(4))}), 8+rng.IntN(17))
+	}
+
+	// Check the invariants hold after every operation in a long random
+	// sequence of mutations.
+	for i := 0; i < 2000; i++ {
+		op := rng.IntN(6)
+		switch op {
+		case 0, 1:
+			trieMap.Insert(randomPrefix(), rng.IntN(8))
+		case 2:
+			trieMap.InsertWeighted(randomPrefix(), rng.IntN(8), uint32(rng.IntN(4)))
+		case 3:
+			trieMap.Remove(randomPrefix())
+		case 4:
+			trieMap.RemoveValue(rng.IntN(8))
+		case 5:
+			trieMap.Update(func(_ netip.Prefix, value int) (int, bool) {
+				return (value + 1) % 8, value != 0
+			})
+		}
+
+		require.NoError(t, trieMap.Validate(), "operation %d (%d)", i, op)
+	}
+}
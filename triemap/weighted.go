@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap
+
+import (
+	"math/rand/v2"
+	"net/netip"
+	"slices"
+)
+
+// InsertWeighted adds value to the set of weighted values associated with
+// prefix, or updates its weight if the value is already present. Unlike
+// Insert, it does not replace any existing values for the prefix.
+//
+// Each prefix ordinarily holds a single value, InsertWeighted extends this to
+// a small weighted set. Get always returns the first value inserted for the
+// prefix (the primary value, see WithLess), GetWeighted selects among the set
+// according to weight. A subsequent Insert for the same prefix replaces the
+// whole set.
+func (t *TrieMap[V]) InsertWeighted(prefix netip.Prefix, value V, weight uint32) {
+	if !prefix.IsValid() {
+		return
+	}
+
+	t.mu.Lock()
+	key := t.keyFor(value)
+	t.trieMap.insertWeighted(prefix, key, weight)
+	// Report the primary value, which may not be the value inserted.
+	value = t.primary(t.trieMap.find(prefix))
+	t.mu.Unlock()
+
+	t.notifyInsert(prefix, value)
+}
+
+// GetWeighted returns a value for the longest prefix matching addr, chosen at
+// random from the values associated with the prefix in proportion to their
+// weights. If all weights are zero, a value is chosen uniformly. If rng is
+// nil, the global random source is used.
+func (t *TrieMap[V]) GetWeighted(addr netip.Addr, rng *rand.Rand) (value V, contains bool) {
+	mu := t.mu.RLockAddr(addr)
+	defer mu.RUnlock()
+
+	v := t.trieMap.get(addr)
+	if v == nil {
+		return
+	}
+
+	intN := rand.IntN
+	if rng != nil {
+		intN = rng.IntN
+	}
+
+	keys := v.keys
+	if len(t.trieMap.disabled) > 0 {
+		keys = slices.DeleteFunc(slices.Clone(keys), func(k weightedKey) bool {
+			return t.trieMap.isDisabled(k.key)
+		})
+	}
+
+	var total uint64
+	for _, k := range keys {
+		total += uint64(k.weight)
+	}
+	if total == 0 {
+		return t.keyToValue[keys[intN(len(keys))].key], true
+	}
+
+	n := uint64(intN(int(total)))
+	for _, k := range keys {
+		if n < uint64(k.weight) {
+			return t.keyToValue[k.key], true
+		}
+		n -= uint64(k.weight)
+	}
+
+	// Unreachable.
+	return t.keyToValue[v.key()], true
+}
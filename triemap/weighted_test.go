@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap_test
+
+import (
+	"math/rand/v2"
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/triemap"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrieMapWeighted(t *testing.T) {
+	trieMap := triemap.New[string]()
+
+	prefix := netip.MustParsePrefix("10.0.0.0/8")
+	trieMap.InsertWeighted(prefix, "a", 1)
+	trieMap.InsertWeighted(prefix, "b", 3)
+	trieMap.InsertWeighted(prefix, "c", 0)
+	trieMap.Insert(netip.MustParsePrefix("10.1.0.0/16"), "d")
+
+	addr := netip.MustParseAddr("10.2.3.4")
+
+	// Get should return the primary value.
+	value, contains := trieMap.Get(addr)
+	require.True(t, contains)
+	require.Equal(t, "a", value)
+
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	counts := make(map[string]int)
+	for i := 0; i < 4000; i++ {
+		value, contains := trieMap.GetWeighted(addr, rng)
+		require.True(t, contains)
+		counts[value]++
+	}
+
+	require.Zero(t, counts["c"])
+	require.InDelta(t, 1000, counts["a"], 150)
+	require.InDelta(t, 3000, counts["b"], 150)
+
+	// A more specific single value prefix should still win.
+	value, contains = trieMap.GetWeighted(netip.MustParseAddr("10.1.2.3"), rng)
+	require.True(t, contains)
+	require.Equal(t, "d", value)
+
+	_, contains = trieMap.GetWeighted(netip.MustParseAddr("192.168.1.1"), rng)
+	require.False(t, contains)
+
+	// Removing a value should only remove it from the set.
+	trieMap.RemoveValue("a")
+
+	value, contains = trieMap.Get(addr)
+	require.True(t, contains)
+	require.Equal(t, "b", value)
+
+	// Insert replaces the whole set.
+	trieMap.Insert(prefix, "e")
+	for i := 0; i < 100; i++ {
+		value, _ := trieMap.GetWeighted(addr, rng)
+		require.Equal(t, "e", value)
+	}
+
+	require.True(t, trieMap.Remove(prefix))
+	_, contains = trieMap.GetWeighted(addr, nil)
+	require.False(t, contains)
+}
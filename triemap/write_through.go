@@ -0,0 +1,95 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap
+
+import "net/netip"
+
+type writeThrough[V comparable] struct {
+	onInsert func(netip.Prefix, V)
+	onRemove func(netip.Prefix)
+}
+
+// SetWriteThrough sets hooks that mirror mutations of the TrieMap to external
+// storage, so that it holds the same prefixes and values as ToMap. Every
+// method that changes the stored prefixes or values reports the change:
+//
+//   - onInsert is called for each prefix inserted or updated by Insert,
+//     InsertBatch, InsertBoth, InsertDefault, InsertNet, InsertWeighted,
+//     InsertTTL, Replace, Merge, Update, UnmarshalJSON and GobDecode, and for
+//     each prefix left with other values by RemoveValue.
+//   - onRemove is called for each prefix removed by Remove, GetAndRemove,
+//     RemoveIf, RemoveValue, Update, Clear, ExpireNow (and so the sweeper
+//     started by StartExpiry), UnmarshalJSON and GobDecode.
+//
+// SetEnabled only affects lookups, so it is not reported. Either hook may be
+// nil. Prefixes are passed in the form they are stored in (see
+// WithPreserveInsertedPrefix).
+//
+// Hooks report a single value per prefix. In multimap mode, and for
+// InsertWeighted, onInsert is called with the prefix's primary value after
+// the mutation (see WithLess) rather than the value inserted, and weights are
+// not reported. A mirror can therefore reproduce the value Get returns for
+// each prefix, but not the full set of values.
+//
+// Hooks run after the mutation has been applied and the lock released, so
+// they may safely call back into the TrieMap. As a consequence, hooks for
+// concurrent mutations may be invoked in a different order than the mutations
+// were applied, callers requiring strict ordering must serialize mutations.
+func (t *TrieMap[V]) SetWriteThrough(onInsert func(netip.Prefix, V), onRemove func(netip.Prefix)) {
+	if onInsert == nil && onRemove == nil {
+		t.writeThrough.Store(nil)
+		return
+	}
+	t.writeThrough.Store(&writeThrough[V]{onInsert: onInsert, onRemove: onRemove})
+}
+
+// notifyInsert invokes the write through insert hook (if any).
+func (t *TrieMap[V]) notifyInsert(prefix netip.Prefix, value V) {
+	if wt := t.writeThrough.Load(); wt != nil && wt.onInsert != nil {
+		wt.onInsert(t.trieMap.normalize(prefix), value)
+	}
+}
+
+// notifyRemove invokes the write through remove hook (if any).
+func (t *TrieMap[V]) notifyRemove(prefix netip.Prefix) {
+	if wt := t.writeThrough.Load(); wt != nil && wt.onRemove != nil {
+		wt.onRemove(t.trieMap.normalize(prefix))
+	}
+}
+
+// hooked returns true if any write through hooks are set.
+func (t *TrieMap[V]) hooked() bool {
+	return t.writeThrough.Load() != nil
+}
+
+// changes describes the current state of the given prefixes for the write
+// through hooks, as the entries still present (with their primary values) and
+// the prefixes that have been removed. The caller must hold the lock.
+func (t *TrieMap[V]) changes(prefixes []netip.Prefix) (inserted []Entry[V], removed []netip.Prefix) {
+	for _, prefix := range prefixes {
+		if v := t.trieMap.find(prefix); v != nil {
+			inserted = append(inserted, Entry[V]{Prefix: prefix, Value: t.primary(v)})
+		} else {
+			removed = append(removed, prefix)
+		}
+	}
+	return inserted, removed
+}
+
+// notifyAll invokes the write through hooks (if any) for every removed prefix
+// and then every inserted entry.
+func (t *TrieMap[V]) notifyAll(inserted []Entry[V], removed []netip.Prefix) {
+	for _, prefix := range removed {
+		t.notifyRemove(prefix)
+	}
+	for _, e := range inserted {
+		t.notifyInsert(e.Prefix, e.Value)
+	}
+}
@@ -0,0 +1,192 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package triemap_test
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/noisysockets/util/triemap"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrieMapWriteThrough(t *testing.T) {
+	trieMap := triemap.New[string]()
+
+	store := make(map[netip.Prefix]string)
+	trieMap.SetWriteThrough(func(prefix netip.Prefix, value string) {
+		// Hooks run outside of the lock, so calling back in is safe.
+		_, _ = trieMap.Get(prefix.Addr())
+		store[prefix] = value
+	}, func(prefix netip.Prefix) {
+		delete(store, prefix)
+	})
+
+	trieMap.Insert(netip.MustParsePrefix("10.1.2.3/8"), "a")
+	trieMap.Insert(netip.MustParsePrefix("10.1.0.0/16"), "b")
+	trieMap.Insert(netip.MustParsePrefix("fd00::/8"), "c")
+
+	require.Equal(t, map[netip.Prefix]string{
+		netip.MustParsePrefix("10.0.0.0/8"):  "a",
+		netip.MustParsePrefix("10.1.0.0/16"): "b",
+		netip.MustParsePrefix("fd00::/8"):    "c",
+	}, store)
+
+	require.True(t, trieMap.Remove(netip.MustParsePrefix("fd00::/8")))
+	require.False(t, trieMap.Remove(netip.MustParsePrefix("fd00::/8")))
+
+	_, _, ok := trieMap.GetAndRemove(netip.MustParseAddr("10.1.0.1"))
+	require.True(t, ok)
+
+	require.Equal(t, trieMap.ToMap(), store)
+
+	// Clearing the hooks stops mirroring.
+	trieMap.SetWriteThrough(nil, nil)
+	trieMap.Insert(netip.MustParsePrefix("192.168.0.0/16"), "d")
+	require.Len(t, store, 1)
+}
+
+func TestTrieMapWriteThroughEveryMutation(t *testing.T) {
+	trieMap := triemap.New[string]()
+
+	store := make(map[netip.Prefix]string)
+	trieMap.SetWriteThrough(func(prefix netip.Prefix, value string) {
+		store[prefix] = value
+	}, func(prefix netip.Prefix) {
+		_, ok := store[prefix]
+		require.True(t, ok, "removed unknown prefix %s", prefix)
+		delete(store, prefix)
+	})
+
+	mutations := []struct {
+		name   string
+		mutate func()
+	}{
+		{"Insert", func() {
+			trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+		}},
+		{"InsertBatch", func() {
+			trieMap.InsertBatch(
+				triemap.Entry[string]{Prefix: netip.MustParsePrefix("10.1.0.0/16"), Value: "b"},
+				triemap.Entry[string]{Prefix: netip.MustParsePrefix("10.2.0.0/16"), Value: "c"},
+			)
+		}},
+		{"InsertBoth", func() {
+			require.NoError(t, trieMap.InsertBoth(netip.MustParsePrefix("172.16.0.0/12"), netip.MustParsePrefix("fd00::/8"), "d"))
+		}},
+		{"InsertDefault", func() {
+			trieMap.InsertDefault("default")
+		}},
+		{"InsertNet", func() {
+			require.NoError(t, trieMap.InsertNet(&net.IPNet{IP: net.IPv4(192, 168, 0, 0), Mask: net.CIDRMask(16, 32)}, "e"))
+		}},
+		{"InsertWeighted", func() {
+			trieMap.InsertWeighted(netip.MustParsePrefix("2001:db8::/32"), "f", 1)
+		}},
+		{"InsertTTL", func() {
+			trieMap.InsertTTL(netip.MustParsePrefix("10.3.0.0/16"), "g", 0)
+		}},
+		{"ExpireNow", func() {
+			require.Equal(t, 1, trieMap.ExpireNow())
+		}},
+		{"Replace", func() {
+			require.True(t, trieMap.Replace(netip.MustParsePrefix("10.2.0.0/16"), "h"))
+		}},
+		{"Merge", func() {
+			other := triemap.New[string]()
+			other.Insert(netip.MustParsePrefix("10.2.0.0/16"), "i")
+			other.Insert(netip.MustParsePrefix("10.4.0.0/16"), "j")
+			trieMap.Merge(other, nil)
+		}},
+		{"Update", func() {
+			trieMap.Update(func(prefix netip.Prefix, value string) (string, bool) {
+				if value == "i" {
+					return "k", true
+				}
+				return value, prefix != netip.MustParsePrefix("10.4.0.0/16")
+			})
+		}},
+		{"Remove", func() {
+			require.True(t, trieMap.Remove(netip.MustParsePrefix("10.0.0.0/8")))
+		}},
+		{"GetAndRemove", func() {
+			_, _, ok := trieMap.GetAndRemove(netip.MustParseAddr("10.1.2.3"))
+			require.True(t, ok)
+		}},
+		{"RemoveIf", func() {
+			require.Equal(t, 1, trieMap.RemoveIf(func(_ netip.Prefix, value string) bool {
+				return value == "e"
+			}))
+		}},
+		{"RemoveValue", func() {
+			trieMap.RemoveValue("d")
+		}},
+		{"UnmarshalJSON", func() {
+			require.NoError(t, trieMap.UnmarshalJSON([]byte(`[{"prefix":"10.2.0.0/16","value":"x"},{"prefix":"10.5.0.0/16","value":"y"}]`)))
+		}},
+		{"GobDecode", func() {
+			other := triemap.New[string]()
+			other.Insert(netip.MustParsePrefix("10.5.0.0/16"), "z")
+			other.Insert(netip.MustParsePrefix("fd00::/8"), "z")
+			data, err := other.GobEncode()
+			require.NoError(t, err)
+			require.NoError(t, trieMap.GobDecode(data))
+		}},
+		{"Clear", func() {
+			trieMap.Clear()
+		}},
+	}
+
+	for _, m := range mutations {
+		before := trieMap.ToMap()
+		m.mutate()
+		require.NotEqual(t, before, trieMap.ToMap(), m.name)
+		require.Equal(t, trieMap.ToMap(), store, m.name)
+	}
+	require.Empty(t, store)
+}
+
+func TestTrieMapWriteThroughMultimap(t *testing.T) {
+	trieMap := triemap.New[string](triemap.WithMultimap(), triemap.WithLess(func(a, b string) bool {
+		return a < b
+	}))
+
+	store := make(map[netip.Prefix]string)
+	trieMap.SetWriteThrough(func(prefix netip.Prefix, value string) {
+		store[prefix] = value
+	}, func(prefix netip.Prefix) {
+		delete(store, prefix)
+	})
+
+	// The primary value of each prefix is reported, rather than the value
+	// added to its set.
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "b")
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "c")
+	require.Equal(t, "b", store[netip.MustParsePrefix("10.0.0.0/8")])
+
+	trieMap.Insert(netip.MustParsePrefix("10.0.0.0/8"), "a")
+	require.Equal(t, "a", store[netip.MustParsePrefix("10.0.0.0/8")])
+
+	trieMap.InsertBatch(
+		triemap.Entry[string]{Prefix: netip.MustParsePrefix("fd00::/8"), Value: "y"},
+		triemap.Entry[string]{Prefix: netip.MustParsePrefix("fd00::/8"), Value: "x"},
+	)
+	require.Equal(t, "x", store[netip.MustParsePrefix("fd00::/8")])
+
+	trieMap.InsertWeighted(netip.MustParsePrefix("fd00::/8"), "z", 10)
+	require.Equal(t, "x", store[netip.MustParsePrefix("fd00::/8")])
+	require.Equal(t, trieMap.ToMap(), store)
+
+	// Removing a value from a prefix with other values updates the primary.
+	trieMap.RemoveValue("a")
+	require.Equal(t, "b", store[netip.MustParsePrefix("10.0.0.0/8")])
+	require.Equal(t, trieMap.ToMap(), store)
+}
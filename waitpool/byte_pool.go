@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package waitpool
+
+import "math/bits"
+
+// BytePool is a WaitPool of byte slices that can hand out buffers of a
+// requested length. It is safe for concurrent use.
+type BytePool struct {
+	*WaitPool[[]byte]
+	// size is the capacity of buffers constructed by the pool's constructor.
+	size int
+}
+
+// NewBytePool creates a new BytePool with a maximum size of max (0 for an
+// unbounded pool), whose newly constructed buffers have a capacity of size.
+func NewBytePool(max uint32, size int, opts ...Option[[]byte]) *BytePool {
+	return &BytePool{
+		WaitPool: New(max, func() []byte { return make([]byte, size) }, opts...),
+		size:     size,
+	}
+}
+
+// GetSized returns a buffer of length n from the pool. If the pooled buffer's
+// capacity is too small, it is discarded and a new buffer is allocated with a
+// capacity of n rounded up to the next power of two (its capacity class), so
+// that the buffer can be reused for similarly sized requests once it is
+// returned to the pool. Otherwise no allocation takes place. At most one
+// buffer is constructed per call.
+//
+// The contents of the buffer are not cleared.
+func (p *BytePool) GetSized(n int) []byte {
+	newSized := func() []byte { return make([]byte, n, capacityClass(n)) }
+
+	// Buffers from the pool's own constructor are only big enough for
+	// requests up to its size.
+	var newFn func() []byte
+	if n > p.size {
+		newFn = newSized
+	}
+
+	p.acquire()
+	buf := p.take(newFn)
+	if cap(buf) < n {
+		p.discard(buf)
+		buf = p.construct(newSized)
+	}
+	return buf[:n]
+}
+
+// Put returns a buffer to the pool, at its full capacity, so that it can be
+// reused by any subsequent GetSized call that fits. Buffers that have grown
+// too large can be discarded with WithMaxItemSize, using cap as the size
+// function.
+func (p *BytePool) Put(buf []byte) {
+	p.WaitPool.Put(buf[:cap(buf)])
+}
+
+// capacityClass rounds n up to the next power of two.
+func capacityClass(n int) int {
+	if n <= 1 {
+		return n
+	}
+	return 1 << bits.Len(uint(n-1))
+}
@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package waitpool_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/noisysockets/util/waitpool"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBytePool(t *testing.T) {
+	p := waitpool.NewBytePool(4, 512)
+	p.SetMaxIdle(time.Minute)
+
+	buf := p.GetSized(100)
+	require.Len(t, buf, 100)
+	require.Equal(t, 512, cap(buf))
+	require.Equal(t, 1, p.Count())
+	p.Put(buf)
+	require.Zero(t, p.Count())
+
+	// Pooled buffers are resized in place.
+	buf = p.GetSized(512)
+	require.Len(t, buf, 512)
+	p.Put(buf)
+	require.Equal(t, int64(1), p.Constructed())
+
+	// Requests that don't fit are rounded up to the next capacity class.
+	buf = p.GetSized(1000)
+	require.Len(t, buf, 1000)
+	require.Equal(t, 1024, cap(buf))
+	require.Equal(t, int64(2), p.Constructed())
+	p.Put(buf)
+
+	// And the larger buffer is filed back for reuse.
+	buf = p.GetSized(1024)
+	require.Equal(t, 1024, cap(buf))
+	require.Equal(t, int64(2), p.Constructed())
+	p.Put(buf)
+
+	// Steady state use doesn't allocate.
+	allocs := testing.AllocsPerRun(100, func() {
+		p.Put(p.GetSized(256))
+	})
+	require.Zero(t, allocs)
+}
+
+func TestBytePoolGetSizedConstructsOnce(t *testing.T) {
+	p := waitpool.NewBytePool(0, 512)
+	p.SetMaxIdle(time.Minute)
+
+	// An empty pool constructs a buffer of the requested size directly.
+	buf := p.GetSized(1000)
+	require.Equal(t, 1024, cap(buf))
+	require.Equal(t, int64(1), p.Constructed())
+
+	// As does a pool whose idle buffer is too small.
+	p.Put(make([]byte, 512))
+	buf = p.GetSized(2000)
+	require.Equal(t, 2048, cap(buf))
+	require.Equal(t, int64(2), p.Constructed())
+}
@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package waitpool
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+)
+
+// PoolInfo describes a named pool.
+type PoolInfo struct {
+	// Name is the name the pool was registered with.
+	Name string
+	// Max is the maximum number of items in use, or 0 if unbounded.
+	Max uint32
+	// Count is the number of items currently in use.
+	Count int
+	// MaxItemSize is the maximum size of items returned to the pool, or 0 if
+	// uncapped, see WithMaxItemSize.
+	MaxItemSize int
+	// HealthChecked is true if pooled items are health checked before being
+	// handed out, see WithHealthCheck.
+	HealthChecked bool
+	// MaxIdle is the maximum time an item may sit idle in the pool, or 0 if
+	// idle items aren't tracked, see SetMaxIdle.
+	MaxIdle time.Duration
+}
+
+var registry struct {
+	sync.Mutex
+	pools map[string]func() PoolInfo
+}
+
+// NewNamed is like New but also registers the pool under the given name in
+// a package level registry, so that its metrics can be retrieved with
+// Registered. Registering a pool replaces any pool previously registered
+// under the same name, so recreated pools don't accumulate. Registered pools
+// are otherwise retained until they are removed with Unregister.
+func NewNamed[T any](name string, max uint32, new func() T, opts ...Option[T]) *WaitPool[T] {
+	p := New(max, new, opts...)
+
+	registry.Lock()
+	if registry.pools == nil {
+		registry.pools = make(map[string]func() PoolInfo)
+	}
+	registry.pools[name] = func() PoolInfo {
+		info := PoolInfo{
+			Name:          name,
+			Max:           p.max,
+			Count:         p.Count(),
+			HealthChecked: p.healthy != nil,
+		}
+		if p.size != nil {
+			info.MaxItemSize = p.maxItemSize
+		}
+		p.idleLock.Lock()
+		info.MaxIdle = p.maxIdle
+		p.idleLock.Unlock()
+		return info
+	}
+	registry.Unlock()
+
+	return p
+}
+
+// Unregister removes the pool registered under the given name, so that the
+// registry no longer retains it. It returns false if no pool was registered
+// under the name.
+func Unregister(name string) bool {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if _, ok := registry.pools[name]; !ok {
+		return false
+	}
+	delete(registry.pools, name)
+	return true
+}
+
+// Registered returns information about every pool created with NewNamed,
+// ordered by name.
+func Registered() []PoolInfo {
+	registry.Lock()
+	pools := make([]func() PoolInfo, 0, len(registry.pools))
+	for _, info := range registry.pools {
+		pools = append(pools, info)
+	}
+	registry.Unlock()
+
+	infos := make([]PoolInfo, len(pools))
+	for i, info := range pools {
+		infos[i] = info()
+	}
+	slices.SortFunc(infos, func(a, b PoolInfo) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return infos
+}
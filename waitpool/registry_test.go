@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: MPL-2.0
+/*
+ * Copyright (C) 2024 The Noisy Sockets Authors.
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at http://mozilla.org/MPL/2.0/.
+ */
+
+package waitpool_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/noisysockets/util/waitpool"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistered(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			_ = waitpool.NewNamed(fmt.Sprintf("%s/concurrent-%d", t.Name(), i), 1, func() int { return i })
+			_ = waitpool.Registered()
+		}(i)
+	}
+	wg.Wait()
+
+	buffers := waitpool.NewNamed(t.Name()+"/buffers", 10, func() []byte { return make([]byte, 512) })
+	_ = buffers.Get()
+	_ = buffers.Get()
+
+	infos := registered(t.Name() + "/")
+	require.Len(t, infos, 11)
+
+	require.Equal(t, waitpool.PoolInfo{Name: t.Name() + "/buffers", Max: 10, Count: 2}, infos[0])
+	require.Equal(t, t.Name()+"/concurrent-0", infos[1].Name)
+
+	// Registering a pool under an existing name replaces it.
+	buffers = waitpool.NewNamed(t.Name()+"/buffers", 20, func() []byte { return make([]byte, 512) })
+	_ = buffers.Get()
+
+	infos = registered(t.Name() + "/")
+	require.Len(t, infos, 11)
+	require.Equal(t, waitpool.PoolInfo{Name: t.Name() + "/buffers", Max: 20, Count: 1}, infos[0])
+
+	// Unregistered pools are no longer retained.
+	require.True(t, waitpool.Unregister(t.Name()+"/buffers"))
+	require.False(t, waitpool.Unregister(t.Name()+"/buffers"))
+	infos = registered(t.Name() + "/")
+	require.Len(t, infos, 10)
+	require.Equal(t, t.Name()+"/concurrent-0", infos[0].Name)
+}
+
+func TestRegisteredLimits(t *testing.T) {
+	p := waitpool.NewNamed(t.Name(), 4, func() []byte { return make([]byte, 512) },
+		waitpool.WithMaxItemSize(func(buf []byte) int { return cap(buf) }, 4096),
+		waitpool.WithHealthCheck(func([]byte) bool { return true }))
+	p.SetMaxIdle(time.Minute)
+	t.Cleanup(func() { waitpool.Unregister(t.Name()) })
+
+	require.Equal(t, []waitpool.PoolInfo{{
+		Name:          t.Name(),
+		Max:           4,
+		MaxItemSize:   4096,
+		HealthChecked: true,
+		MaxIdle:       time.Minute,
+	}}, registered(t.Name()))
+}
+
+// registered returns the registered pools whose name starts with prefix.
+func registered(prefix string) []waitpool.PoolInfo {
+	var infos []waitpool.PoolInfo
+	for _, info := range waitpool.Registered() {
+		if strings.HasPrefix(info.Name, prefix) {
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
@@ -33,8 +33,10 @@
 package waitpool
 
 import (
+	"context"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // WaitPool is a bounded sync.Pool. It is safe for concurrent use.
@@ -44,37 +46,203 @@ type WaitPool[T any] struct {
 	lock  sync.Mutex
 	count atomic.Int32
 	max   uint32
+	// new constructs items, it can be replaced at runtime with SetNew.
+	new atomic.Pointer[func() T]
+	// highWater is the largest number of items that have been in use at once,
+	// it is protected by lock.
+	highWater int32
+	// constructed is the number of items ever constructed by new.
+	constructed atomic.Int64
+	// When a maximum idle time is configured, items are kept in a LIFO stack
+	// (rather than the sync.Pool) so that their idle time can be tracked.
+	idleLock  sync.Mutex
+	idle      []idleItem[T]
+	maxIdle   time.Duration
+	onDiscard func(T)
+	// When a size function is configured, items larger than maxItemSize are
+	// discarded rather than returned to the pool.
+	size        func(T) int
+	maxItemSize int
+	// When a health check is configured, items that fail it are discarded by
+	// Get rather than handed out.
+	healthy func(T) bool
+}
+
+type idleItem[T any] struct {
+	value T
+	since time.Time
+}
+
+// Option configures optional behavior of a WaitPool.
+type Option[T any] func(*WaitPool[T])
+
+// WithMaxItemSize caps the size of items returned to the pool. Put discards
+// items for which size returns more than max, rather than pooling them, eg.
+// byte slices that have grown far beyond their original capacity. A nil size
+// function disables the cap.
+func WithMaxItemSize[T any](size func(T) int, max int) Option[T] {
+	return func(p *WaitPool[T]) {
+		p.size = size
+		p.maxItemSize = max
+	}
+}
+
+// maxHealthChecks is the number of pooled items Get will check before giving
+// up on the pool and constructing a fresh item, which is not checked.
+const maxHealthChecks = 3
+
+// WithHealthCheck configures a function that is called with each pooled item
+// before Get (or GetUpTo) returns it, eg. to check that a connection is still
+// alive. Items for which healthy returns false are discarded and another item
+// is tried, after a few attempts a fresh item is constructed instead. Freshly
+// constructed items are returned without being checked, so a Get never
+// constructs more than one item.
+func WithHealthCheck[T any](healthy func(T) bool) Option[T] {
+	return func(p *WaitPool[T]) {
+		p.healthy = healthy
+	}
 }
 
 // New creates a new WaitPool with a maximum size of max. If max is 0, the pool
 // is unbounded.
-func New[T any](max uint32, new func() T) *WaitPool[T] {
-	p := &WaitPool[T]{pool: sync.Pool{New: func() any { return new() }}, max: max}
+func New[T any](max uint32, new func() T, opts ...Option[T]) *WaitPool[T] {
+	p := &WaitPool[T]{max: max}
+	p.new.Store(&new)
 	p.cond = sync.Cond{L: &p.lock}
+	for _, opt := range opts {
+		opt(p)
+	}
 	return p
 }
 
+// SetMaxIdle sets the maximum amount of time an item may sit idle in the pool.
+// Items that have been idle for longer are discarded by Get and a fresh item
+// is constructed instead. A value of 0 (the default) disables idle tracking
+// and discards any items currently held idle.
+//
+// When idle tracking is enabled, any items held by the underlying sync.Pool
+// are moved to the idle stack, so that they are tracked (and eventually
+// discarded) like any other idle item. Items the sync.Pool has already
+// dropped, eg. during garbage collection, are never passed to the discard
+// hook.
+func (p *WaitPool[T]) SetMaxIdle(d time.Duration) {
+	p.idleLock.Lock()
+	if d != 0 && p.maxIdle == 0 {
+		now := time.Now()
+		for {
+			x, ok := p.pool.Get().(T)
+			if !ok {
+				break
+			}
+			p.idle = append(p.idle, idleItem[T]{value: x, since: now})
+		}
+	}
+	p.maxIdle = d
+	var discarded []idleItem[T]
+	if d == 0 {
+		discarded, p.idle = p.idle, nil
+	}
+	p.idleLock.Unlock()
+
+	for _, item := range discarded {
+		p.discard(item.value)
+	}
+}
+
+// SetNew replaces the function used to construct new items, eg. to change the
+// size of newly allocated buffers after a configuration reload. Items already
+// in the pool, or in use, are unaffected and will continue to be handed out.
+// It is safe to call concurrently with Get and Put.
+func (p *WaitPool[T]) SetNew(new func() T) {
+	p.new.Store(&new)
+}
+
+// SetOnDiscard sets a function that is called with every item the pool
+// discards, eg. to close a stale connection.
+func (p *WaitPool[T]) SetOnDiscard(fn func(T)) {
+	p.idleLock.Lock()
+	p.onDiscard = fn
+	p.idleLock.Unlock()
+}
+
 // Get returns an item from the pool. If the pool is bounded and all items are
 // in use, Get will block until an item is available.
 func (p *WaitPool[T]) Get() T {
+	p.acquire()
+	return p.take(nil)
+}
+
+// acquire blocks until an item can be taken from a bounded pool, and counts
+// it as in use.
+func (p *WaitPool[T]) acquire() {
+	if p.max == 0 {
+		return
+	}
+	p.lock.Lock()
+	for uint32(p.count.Load()) >= p.max {
+		p.cond.Wait()
+	}
+	if n := p.count.Add(1); n > p.highWater {
+		p.highWater = n
+	}
+	p.lock.Unlock()
+}
+
+// GetUpTo returns between 1 and n items from the pool. If the pool is bounded
+// and all items are in use, GetUpTo blocks until at least one item is
+// available, and then returns as many items as are available (up to n) without
+// blocking further. If the context is done before any items are available, nil
+// is returned. Each returned item must be returned to the pool with Put.
+func (p *WaitPool[T]) GetUpTo(ctx context.Context, n int) []T {
+	if n <= 0 {
+		return nil
+	}
+
 	if p.max != 0 {
+		// Wake up the waiters below if the context is done.
+		stop := context.AfterFunc(ctx, func() {
+			p.lock.Lock()
+			p.cond.Broadcast()
+			p.lock.Unlock()
+		})
+		defer stop()
+
 		p.lock.Lock()
 		for uint32(p.count.Load()) >= p.max {
+			if ctx.Err() != nil {
+				p.lock.Unlock()
+				return nil
+			}
 			p.cond.Wait()
 		}
-		p.count.Add(1)
+		n = min(n, int(p.max)-int(p.count.Load()))
+		if count := p.count.Add(int32(n)); count > p.highWater {
+			p.highWater = count
+		}
 		p.lock.Unlock()
 	}
-	return p.pool.Get().(T)
+
+	items := make([]T, n)
+	for i := range items {
+		items[i] = p.take(nil)
+	}
+	return items
 }
 
-// Put adds x to the pool.
+// Put adds x to the pool. If a maximum item size is configured and x exceeds
+// it, x is discarded instead.
 func (p *WaitPool[T]) Put(x T) {
-	p.pool.Put(x)
+	if p.size != nil && p.size(x) > p.maxItemSize {
+		p.discard(x)
+	} else {
+		p.release(x)
+	}
 	if p.max == 0 {
 		return
 	}
+	p.lock.Lock()
 	p.count.Add(-1)
+	p.lock.Unlock()
 	p.cond.Signal()
 }
 
@@ -82,3 +250,173 @@ func (p *WaitPool[T]) Put(x T) {
 func (p *WaitPool[T]) Count() int {
 	return int(p.count.Load())
 }
+
+// Constructed returns the total number of items that have ever been
+// constructed by the pool. A value that keeps climbing under a steady load
+// indicates that items are not being returned to the pool.
+func (p *WaitPool[T]) Constructed() int64 {
+	return p.constructed.Load()
+}
+
+// DrainAll removes and returns every idle item held by the pool, eg. so that
+// they can be closed at shutdown. Items currently in use are not included.
+// The pool should not be used afterwards, unless it is re-warmed.
+//
+// Idle items can only be enumerated when idle tracking is enabled (see
+// SetMaxIdle), otherwise they are held by a sync.Pool and DrainAll returns
+// ok=false.
+func (p *WaitPool[T]) DrainAll() (items []T, ok bool) {
+	p.idleLock.Lock()
+	if p.maxIdle == 0 {
+		p.idleLock.Unlock()
+		return nil, false
+	}
+	idle := p.idle
+	p.idle = nil
+	p.idleLock.Unlock()
+
+	if len(idle) == 0 {
+		return nil, true
+	}
+
+	items = make([]T, len(idle))
+	for i, item := range idle {
+		items[i] = item.value
+	}
+	return items, true
+}
+
+// Stats is a snapshot of the state of a WaitPool.
+type Stats struct {
+	// InUse is the number of items currently taken from the pool.
+	InUse int
+	// Max is the maximum number of items that may be in use at once, 0 if the
+	// pool is unbounded.
+	Max int
+	// Available is the number of items that can be taken without blocking.
+	// It is always 0 for unbounded pools, which never block.
+	Available int
+	// HighWater is the largest number of items that have been in use at once.
+	HighWater int
+	// Idle is the number of items held idle in the pool. It is only tracked
+	// when a maximum idle time is configured, see SetMaxIdle.
+	Idle int
+	// Constructed is the total number of items ever constructed by the pool.
+	Constructed int64
+}
+
+// Stats returns a snapshot of the pool's counters. InUse, Available and
+// HighWater are captured together under the pool lock and are always
+// consistent with one another. Idle and Constructed are read separately and
+// are best-effort, they may be slightly ahead of or behind the other fields.
+//
+// In use counts are only tracked for bounded pools.
+func (p *WaitPool[T]) Stats() Stats {
+	var stats Stats
+
+	p.lock.Lock()
+	stats.InUse = int(p.count.Load())
+	stats.Max = int(p.max)
+	if p.max != 0 {
+		stats.Available = int(p.max) - stats.InUse
+	}
+	stats.HighWater = int(p.highWater)
+	p.lock.Unlock()
+
+	p.idleLock.Lock()
+	stats.Idle = len(p.idle)
+	p.idleLock.Unlock()
+
+	stats.Constructed = p.constructed.Load()
+
+	return stats
+}
+
+// take returns a healthy idle item from the pool, or constructs a new one
+// with newFn (or the pool's constructor if newFn is nil).
+func (p *WaitPool[T]) take(newFn func() T) T {
+	if p.healthy == nil {
+		if x, ok := p.takeIdle(); ok {
+			return x
+		}
+		return p.construct(newFn)
+	}
+
+	for range maxHealthChecks {
+		x, ok := p.takeIdle()
+		if !ok {
+			return p.construct(newFn)
+		}
+		if p.healthy(x) {
+			return x
+		}
+		p.discard(x)
+	}
+	return p.construct(newFn)
+}
+
+// takeIdle returns an idle item from the pool, if there is one.
+func (p *WaitPool[T]) takeIdle() (x T, ok bool) {
+	p.idleLock.Lock()
+	if p.maxIdle == 0 {
+		p.idleLock.Unlock()
+		x, ok = p.pool.Get().(T)
+		return x, ok
+	}
+
+	if n := len(p.idle); n > 0 {
+		item := p.idle[n-1]
+		if time.Since(item.since) <= p.maxIdle {
+			p.idle = p.idle[:n-1]
+			p.idleLock.Unlock()
+			return item.value, true
+		}
+
+		// As the stack is LIFO, every item below an expired item has been
+		// idle for even longer, so discard them all.
+		expired := p.idle
+		p.idle = nil
+		p.idleLock.Unlock()
+
+		for _, item := range expired {
+			p.discard(item.value)
+		}
+	} else {
+		p.idleLock.Unlock()
+	}
+
+	return x, false
+}
+
+// construct creates a new item with newFn, or the pool's constructor if newFn
+// is nil.
+func (p *WaitPool[T]) construct(newFn func() T) T {
+	p.constructed.Add(1)
+	if newFn == nil {
+		newFn = *p.new.Load()
+	}
+	return newFn()
+}
+
+// release returns an item to the idle store.
+func (p *WaitPool[T]) release(x T) {
+	p.idleLock.Lock()
+	if p.maxIdle == 0 {
+		p.idleLock.Unlock()
+		p.pool.Put(x)
+		return
+	}
+	p.idle = append(p.idle, idleItem[T]{value: x, since: time.Now()})
+	p.idleLock.Unlock()
+}
+
+// discard passes an item that is no longer wanted to the discard hook.
+func (p *WaitPool[T]) discard(x T) {
+	p.idleLock.Lock()
+	onDiscard := p.onDiscard
+	p.idleLock.Unlock()
+
+	if onDiscard != nil {
+		onDiscard(x)
+	}
+}
@@ -10,6 +10,8 @@
 package waitpool_test
 
 import (
+	"context"
+	"sync"
 	"testing"
 	"time"
 
@@ -58,3 +60,316 @@ func TestWaitPool(t *testing.T) {
 	buf := p.Get()
 	require.Len(t, buf, 512)
 }
+
+func TestWaitPoolMaxIdle(t *testing.T) {
+	type conn struct{ id int }
+
+	var nextID int
+	p := waitpool.New(2, func() *conn {
+		nextID++
+		return &conn{id: nextID}
+	})
+	p.SetMaxIdle(50 * time.Millisecond)
+
+	var discarded []*conn
+	p.SetOnDiscard(func(c *conn) {
+		discarded = append(discarded, c)
+	})
+
+	a := p.Get()
+	b := p.Get()
+	p.Put(a)
+	p.Put(b)
+
+	// The most recently returned item should be handed out first.
+	c := p.Get()
+	require.Equal(t, b, c)
+	p.Put(c)
+
+	time.Sleep(100 * time.Millisecond)
+
+	// All idle items have expired, so a fresh one should be constructed.
+	c = p.Get()
+	require.Equal(t, 3, c.id)
+	require.ElementsMatch(t, []*conn{a, b}, discarded)
+	p.Put(c)
+
+	// Disabling idle tracking should discard any idle items.
+	p.SetMaxIdle(0)
+	require.Contains(t, discarded, c)
+}
+
+func TestWaitPoolConstructed(t *testing.T) {
+	p := waitpool.New(0, func() *[]byte {
+		b := make([]byte, 512)
+		return &b
+	})
+	p.SetMaxIdle(time.Minute)
+
+	require.Zero(t, p.Constructed())
+
+	a := p.Get()
+	b := p.Get()
+	require.Equal(t, int64(2), p.Constructed())
+
+	p.Put(a)
+	p.Put(b)
+
+	// Reusing pooled items should not construct new ones.
+	for i := 0; i < 10; i++ {
+		p.Put(p.Get())
+	}
+	require.Equal(t, int64(2), p.Constructed())
+
+	// Leaking items forces new ones to be constructed.
+	for i := 0; i < 10; i++ {
+		_ = p.Get()
+	}
+	require.Equal(t, int64(10), p.Constructed())
+}
+
+func TestWaitPoolSetNew(t *testing.T) {
+	p := waitpool.New(4, func() *[]byte {
+		b := make([]byte, 512)
+		return &b
+	})
+	p.SetMaxIdle(time.Minute)
+
+	a := p.Get()
+	p.Put(a)
+
+	p.SetNew(func() *[]byte {
+		b := make([]byte, 1024)
+		return &b
+	})
+
+	// Pooled items are unaffected.
+	b := p.Get()
+	require.Len(t, *b, 512)
+
+	// But new items use the new factory.
+	c := p.Get()
+	require.Len(t, *c, 1024)
+
+	p.Put(b)
+	p.Put(c)
+
+	// Swapping the factory is safe under concurrent use.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				p.Put(p.Get())
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		size := 512 * (i%2 + 1)
+		p.SetNew(func() *[]byte {
+			b := make([]byte, size)
+			return &b
+		})
+	}
+	wg.Wait()
+
+	require.Zero(t, p.Count())
+}
+
+func TestWaitPoolStats(t *testing.T) {
+	p := waitpool.New(4, func() int { return 0 })
+	p.SetMaxIdle(time.Minute)
+
+	require.Equal(t, waitpool.Stats{Max: 4, Available: 4}, p.Stats())
+
+	a := p.Get()
+	b := p.Get()
+	c := p.Get()
+	p.Put(c)
+
+	require.Equal(t, waitpool.Stats{
+		InUse:       2,
+		Max:         4,
+		Available:   2,
+		HighWater:   3,
+		Idle:        1,
+		Constructed: 3,
+	}, p.Stats())
+
+	p.Put(a)
+	p.Put(b)
+
+	stats := p.Stats()
+	require.Zero(t, stats.InUse)
+	require.Equal(t, 4, stats.Available)
+	require.Equal(t, 3, stats.HighWater)
+	require.Equal(t, 3, stats.Idle)
+}
+
+func TestWaitPoolMaxItemSize(t *testing.T) {
+	var discarded int
+	p := waitpool.New(0, func() *[]byte {
+		b := make([]byte, 0, 512)
+		return &b
+	}, waitpool.WithMaxItemSize(func(b *[]byte) int { return cap(*b) }, 1024))
+	p.SetMaxIdle(time.Minute)
+	p.SetOnDiscard(func(*[]byte) { discarded++ })
+
+	small := p.Get()
+	large := p.Get()
+	*large = append(*large, make([]byte, 4096)...)
+
+	p.Put(small)
+	p.Put(large)
+
+	require.Equal(t, 1, discarded)
+	require.Equal(t, 1, p.Stats().Idle)
+
+	// The small buffer is reused.
+	require.Same(t, small, p.Get())
+}
+
+func TestWaitPoolHealthCheck(t *testing.T) {
+	type conn struct {
+		id   int
+		dead bool
+	}
+
+	var next int
+	var discarded []int
+	p := waitpool.New(2, func() *conn {
+		next++
+		return &conn{id: next}
+	}, waitpool.WithHealthCheck(func(c *conn) bool { return !c.dead }))
+	p.SetMaxIdle(time.Minute)
+	p.SetOnDiscard(func(c *conn) { discarded = append(discarded, c.id) })
+
+	a, b := p.Get(), p.Get()
+	p.Put(a)
+	p.Put(b)
+
+	// The most recently used connection has died, so it's discarded and the
+	// next one is handed out instead.
+	b.dead = true
+	require.Same(t, a, p.Get())
+	require.Equal(t, []int{b.id}, discarded)
+	require.Equal(t, 1, p.Count())
+
+	// Every pooled connection has died, so a fresh one is constructed.
+	a.dead = true
+	p.Put(a)
+	c := p.Get()
+	require.Equal(t, 3, c.id)
+	require.Equal(t, []int{b.id, a.id}, discarded)
+	require.Equal(t, 1, p.Count())
+
+	p.Put(c)
+	require.Zero(t, p.Count())
+
+	// Freshly constructed items are returned unchecked, so a Get on an empty
+	// pool constructs a single item.
+	var checks int
+	p = waitpool.New(0, func() *conn {
+		next++
+		return &conn{id: next, dead: true}
+	}, waitpool.WithHealthCheck(func(c *conn) bool {
+		checks++
+		return !c.dead
+	}))
+
+	require.True(t, p.Get().dead)
+	require.Equal(t, int64(1), p.Constructed())
+	require.Zero(t, checks)
+
+	// Once the retry limit is reached, a fresh item is constructed.
+	for range 5 {
+		p.Put(&conn{dead: true})
+	}
+	require.True(t, p.Get().dead)
+	require.LessOrEqual(t, checks, 3)
+	require.Equal(t, int64(2), p.Constructed())
+}
+
+func TestWaitPoolDrainAll(t *testing.T) {
+	var next int
+	p := waitpool.New(0, func() int {
+		next++
+		return next
+	})
+	p.SetMaxIdle(time.Minute)
+
+	a, b, c := p.Get(), p.Get(), p.Get()
+	p.Put(a)
+	p.Put(b)
+
+	// In use items are not drained.
+	items, ok := p.DrainAll()
+	require.True(t, ok)
+	require.ElementsMatch(t, []int{a, b}, items)
+	items, ok = p.DrainAll()
+	require.True(t, ok)
+	require.Empty(t, items)
+	require.Zero(t, p.Stats().Idle)
+
+	p.Put(c)
+	items, _ = p.DrainAll()
+	require.Equal(t, []int{c}, items)
+}
+
+func TestWaitPoolDrainAllUntracked(t *testing.T) {
+	var next int
+	p := waitpool.New(0, func() int {
+		next++
+		return next
+	})
+
+	// Without idle tracking, the idle items can't be enumerated.
+	a, b := p.Get(), p.Get()
+	p.Put(a)
+	p.Put(b)
+	items, ok := p.DrainAll()
+	require.False(t, ok)
+	require.Nil(t, items)
+
+	// Enabling it adopts the items held by the sync.Pool (which may drop
+	// some of them at any time).
+	p.SetMaxIdle(time.Minute)
+	items, ok = p.DrainAll()
+	require.True(t, ok)
+	require.Subset(t, []int{a, b}, items)
+}
+
+func TestWaitPoolGetUpTo(t *testing.T) {
+	p := waitpool.New(4, func() int { return 0 })
+	p.SetMaxIdle(time.Minute)
+
+	held := p.Get()
+
+	// Only three items are available.
+	items := p.GetUpTo(context.Background(), 10)
+	require.Len(t, items, 3)
+	require.Equal(t, 4, p.Count())
+
+	// No items are available, so wait for the context.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	t.Cleanup(cancel)
+
+	require.Nil(t, p.GetUpTo(ctx, 2))
+	require.Equal(t, 4, p.Count())
+
+	// Blocks until an item is returned.
+	go p.Put(held)
+
+	items = p.GetUpTo(context.Background(), 2)
+	require.Len(t, items, 1)
+	require.Equal(t, 4, p.Count())
+
+	stats := p.Stats()
+	require.Zero(t, stats.Available)
+	require.Equal(t, 4, stats.HighWater)
+
+	// Unbounded pools never block.
+	unbounded := waitpool.New(0, func() int { return 0 })
+	require.Len(t, unbounded.GetUpTo(context.Background(), 5), 5)
+}